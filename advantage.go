@@ -0,0 +1,55 @@
+package rating
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// AdvantageSample is a past match for estimating the advantage.
+// Score is the score of the advantaged side, such as the home team or the first player.
+type AdvantageSample struct {
+	Advantaged Rating
+	Opponent   Rating
+	Score      float64
+}
+
+// EstimateAdvantage fits the advantage on the strength scale from the match history by maximum likelihood.
+// The ratings of the samples should be the ratings before each match.
+func EstimateAdvantage(samples []AdvantageSample) (float64, error) {
+	return DefaultScale.EstimateAdvantage(samples)
+}
+
+// EstimateAdvantage is like EstimateAdvantage, and returns the advantage on this scale.
+func (s Scale) EstimateAdvantage(samples []AdvantageSample) (float64, error) {
+	if len(samples) == 0 {
+		return 0.0, errors.New("no samples for estimating advantage")
+	}
+	// Newton-Raphson method on the Glicko-2 scale, the log-likelihood is concave.
+	a := 0.0
+	for i := 0; i < iterationLimit; i++ {
+		grad, hess := 0.0, 0.0
+		for _, sample := range samples {
+			if sample.Score < 0.0 || sample.Score > 1.0 {
+				return 0.0, errors.New("score must be 0 to 1 (win = 1, lose = 0, draw = 0.5)")
+			}
+			phi := math.Hypot(sample.Advantaged.phi, sample.Opponent.phi)
+			valg := fg(phi)
+			valE := fE(sample.Advantaged.mu+a, sample.Opponent.mu, phi)
+			grad += valg * (sample.Score - valE)
+			hess -= valg * valg * valE * (1.0 - valE)
+		}
+		if hess == 0.0 {
+			return 0.0, errors.New("can not estimate advantage, samples are degenerate")
+		}
+		diff := grad / hess
+		a -= diff
+		if math.IsNaN(a) || math.IsInf(a, 0) {
+			return 0.0, errors.New("can not estimate advantage, the iteration diverged")
+		}
+		if math.Abs(diff) <= epsiron {
+			return a * s.factor(), nil
+		}
+	}
+	return 0.0, errors.New("can not estimate advantage, the iteration did not converge")
+}
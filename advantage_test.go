@@ -0,0 +1,61 @@
+package rating_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/mashiike/rating"
+)
+
+func TestApplyMatchWithAdvantage(t *testing.T) {
+	player := rating.New(1500.0, 200.0, 0.06)
+	opponent := rating.New(1550.0, 100.0, 0.06)
+	withAdvantage := rating.NewEstimated(player)
+	if err := withAdvantage.ApplyMatchWithAdvantage(opponent, rating.ScoreWin, 60.0); err != nil {
+		t.Fatal(err)
+	}
+	shifted := rating.NewEstimated(player)
+	if err := shifted.ApplyMatch(opponent.Shift(-60.0), rating.ScoreWin); err != nil {
+		t.Fatal(err)
+	}
+	if withAdvantage.Rating() != shifted.Rating() {
+		t.Errorf("got %v, expected %v", withAdvantage.Rating(), shifted.Rating())
+	}
+	if got, expected := player.WinProbWithAdvantage(opponent, 60.0), player.WinProb(opponent); got <= expected {
+		t.Errorf("advantage should increase win prob: got %v, without %v", got, expected)
+	}
+}
+
+func TestEstimateAdvantage(t *testing.T) {
+	const advantage = 80.0
+	rnd := rand.New(rand.NewSource(1))
+	samples := make([]rating.AdvantageSample, 0, 5000)
+	for i := 0; i < cap(samples); i++ {
+		home := rating.New(1300.0+rnd.Float64()*400.0, 30.0, 0.06)
+		away := rating.New(1300.0+rnd.Float64()*400.0, 30.0, 0.06)
+		score := rating.ScoreLose
+		if rnd.Float64() < home.Shift(advantage).WinProb(away) {
+			score = rating.ScoreWin
+		}
+		samples = append(samples, rating.AdvantageSample{Advantaged: home, Opponent: away, Score: score})
+	}
+	got, err := rating.EstimateAdvantage(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got-advantage) > 20.0 {
+		t.Errorf("EstimateAdvantage got %v, expected about %v", got, advantage)
+	}
+	scale := rating.NewScale(25.0, 25.0/3.0)
+	onScale, err := scale.EstimateAdvantage(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := got * scale.Factor / rating.DefaultScale.Factor; math.Abs(onScale-expected) > 1e-9 {
+		t.Errorf("Scale.EstimateAdvantage got %v, expected %v", onScale, expected)
+	}
+	if _, err := rating.EstimateAdvantage(nil); err == nil {
+		t.Error("no samples should be error")
+	}
+}
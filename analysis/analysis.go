@@ -0,0 +1,151 @@
+//Package analysis detects suspicious rating movements from match outcomes and rating time series,
+//such as smurf accounts, win trading and volatility spikes.
+//Each detector returns flags with a score, the higher the more suspicious, and the evidence behind it.
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/mashiike/rating/ratingutil"
+)
+
+//Kind is the kind of suspicious movement
+type Kind int
+
+//Kind constants
+const (
+	//Smurf is a new account with an improbable win streak
+	Smurf Kind = iota + 1
+	//WinTrading is a pair of players repeatedly trading wins
+	WinTrading
+	//VolatilitySpike is a sudden increase of the volatility
+	VolatilitySpike
+)
+
+//String is implements fmt.Stringer
+func (k Kind) String() string {
+	switch k {
+	case Smurf:
+		return "smurf"
+	case WinTrading:
+		return "win_trading"
+	case VolatilitySpike:
+		return "volatility_spike"
+	}
+	return "unknown"
+}
+
+//Flag is a detected suspicious movement
+type Flag struct {
+	Kind    Kind
+	Players []string
+	//Score is the strength of the suspicion, comparable between the flags of the same Kind.
+	Score    float64
+	Evidence []Evidence
+}
+
+//String is implements fmt.Stringer
+func (f Flag) String() string {
+	return fmt.Sprintf("%s%v(%0.2f)", f.Kind, f.Players, f.Score)
+}
+
+//Evidence is a match or a history point supporting a Flag
+type Evidence struct {
+	At      time.Time
+	MatchID string
+	Detail  string
+	//Value is the measured value, such as the win probability of the match or the volatility ratio.
+	Value float64
+}
+
+//Outcome is the result of a match from the view of a player, with the ratings before the match
+type Outcome struct {
+	At             time.Time
+	MatchID        string
+	Player         string
+	Opponent       string
+	Score          float64
+	Rating         rating.Rating
+	OpponentRating rating.Rating
+}
+
+//Outcomes converts the event of an applied match to the outcomes between every pair of Team / Player, as in AsRoundrobin.
+//Use it in ratingutil.Hooks.OnAfterApply to collect the outcomes.
+func Outcomes(e *ratingutil.ApplyEvent) []Outcome {
+	outcomes := make([]Outcome, 0, len(e.Scores)*(len(e.Scores)-1))
+	for target, score1 := range e.Scores {
+		for opponent, score2 := range e.Scores {
+			if target == opponent {
+				continue
+			}
+			score := rating.ScoreLose
+			if score1 > score2 {
+				score = rating.ScoreWin
+			}
+			if score1 == score2 {
+				score = rating.ScoreDraw
+			}
+			outcomes = append(outcomes, Outcome{
+				At:             e.At,
+				MatchID:        e.Match.ID(),
+				Player:         target.Name(),
+				Opponent:       opponent.Name(),
+				Score:          score,
+				Rating:         e.Before[target],
+				OpponentRating: e.Before[opponent],
+			})
+		}
+	}
+	sortOutcomes(outcomes)
+	return outcomes
+}
+
+//mirror returns the outcome from the view of the opponent
+func (o Outcome) mirror() Outcome {
+	o.Player, o.Opponent = o.Opponent, o.Player
+	o.Rating, o.OpponentRating = o.OpponentRating, o.Rating
+	o.Score = 1.0 - o.Score
+	return o
+}
+
+//gameKey identifies the match of the outcome, a multiplayer match gives several outcomes to a player
+func (o Outcome) gameKey() string {
+	return fmt.Sprintf("%s\x00%d", o.MatchID, o.At.UnixNano())
+}
+
+//byPlayer groups the outcomes by the player in time order
+func byPlayer(outcomes []Outcome) map[string][]Outcome {
+	grouped := make(map[string][]Outcome)
+	for _, o := range outcomes {
+		grouped[o.Player] = append(grouped[o.Player], o)
+	}
+	for _, list := range grouped {
+		sortOutcomes(list)
+	}
+	return grouped
+}
+
+func sortOutcomes(outcomes []Outcome) {
+	sort.SliceStable(outcomes, func(i, j int) bool {
+		if !outcomes[i].At.Equal(outcomes[j].At) {
+			return outcomes[i].At.Before(outcomes[j].At)
+		}
+		if outcomes[i].Player != outcomes[j].Player {
+			return outcomes[i].Player < outcomes[j].Player
+		}
+		return outcomes[i].Opponent < outcomes[j].Opponent
+	})
+}
+
+//sortFlags orders the flags by the score descending, then by the players
+func sortFlags(flags []Flag) {
+	sort.SliceStable(flags, func(i, j int) bool {
+		if flags[i].Score != flags[j].Score {
+			return flags[i].Score > flags[j].Score
+		}
+		return fmt.Sprint(flags[i].Players) < fmt.Sprint(flags[j].Players)
+	})
+}
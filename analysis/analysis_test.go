@@ -0,0 +1,141 @@
+package analysis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/mashiike/rating/analysis"
+	"github.com/mashiike/rating/ratingutil"
+)
+
+var start = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestDetectSmurfs(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig())
+	var outcomes []analysis.Outcome
+	svc.Hooks.OnAfterApply(func(e *ratingutil.ApplyEvent) {
+		outcomes = append(outcomes, analysis.Outcomes(e)...)
+	})
+	smurf := svc.NewDefaultPlayer("smurf")
+	rookie := svc.NewDefaultPlayer("rookie")
+	for i := 0; i < 8; i++ {
+		pro := svc.NewPlayerOnScale("pro", 2100.0, 60.0, start)
+		match, _ := svc.NewMatch(smurf, pro)
+		match.Add(smurf, 1.0)
+		if err := svc.ApplyWithTime(match, start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		average := svc.NewPlayerOnScale("average", 1500.0, 60.0, start)
+		match, _ = svc.NewMatch(rookie, average)
+		if i%2 == 0 {
+			match.Add(rookie, 1.0)
+		} else {
+			match.Add(average, 1.0)
+		}
+		if err := svc.ApplyWithTime(match, start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	flags := analysis.DetectSmurfs(outcomes, analysis.DefaultSmurfOptions)
+	if len(flags) != 1 {
+		t.Fatalf("flags = %v", flags)
+	}
+	if flags[0].Kind != analysis.Smurf || flags[0].Players[0] != "smurf" {
+		t.Errorf("flag = %v", flags[0])
+	}
+	if flags[0].Score < 2.0 {
+		t.Errorf("score = %v, want probability below 1%%", flags[0].Score)
+	}
+	if len(flags[0].Evidence) != 8 {
+		t.Errorf("evidence = %d matches", len(flags[0].Evidence))
+	}
+
+	//the games before the outcomes count for the new account
+	for _, c := range []struct {
+		played   int
+		expected int
+	}{{12, 1}, {16, 0}, {20, 0}} {
+		opts := analysis.DefaultSmurfOptions
+		opts.PlayedBefore = func(player string) int {
+			return c.played
+		}
+		if flags := analysis.DetectSmurfs(outcomes, opts); len(flags) != c.expected {
+			t.Errorf("played before %d: flags = %v", c.played, flags)
+		}
+	}
+}
+
+func TestDetectWinTrading(t *testing.T) {
+	r := rating.Default(0.06)
+	var outcomes []analysis.Outcome
+	add := func(at time.Time, winner, loser string) {
+		outcomes = append(outcomes,
+			analysis.Outcome{At: at, Player: winner, Opponent: loser, Score: rating.ScoreWin, Rating: r, OpponentRating: r},
+			analysis.Outcome{At: at, Player: loser, Opponent: winner, Score: rating.ScoreLose, Rating: r, OpponentRating: r},
+		)
+	}
+	for i := 0; i < 8; i++ {
+		at := start.Add(time.Duration(i) * time.Hour)
+		if i%2 == 0 {
+			add(at, "alice", "bob")
+		} else {
+			add(at, "bob", "alice")
+		}
+		//carol always beats dave, it is not trading
+		add(at, "carol", "dave")
+	}
+	flags := analysis.DetectWinTrading(outcomes, analysis.DefaultWinTradingOptions)
+	if len(flags) != 1 {
+		t.Fatalf("flags = %v", flags)
+	}
+	if flags[0].Players[0] != "alice" || flags[0].Players[1] != "bob" {
+		t.Errorf("players = %v", flags[0].Players)
+	}
+	if flags[0].Score != 1.0 {
+		t.Errorf("score = %v", flags[0].Score)
+	}
+	if len(flags[0].Evidence) != 8 {
+		t.Errorf("evidence = %d matches", len(flags[0].Evidence))
+	}
+
+	//the outcomes from the view of the lexically larger player only
+	var larger []analysis.Outcome
+	for _, o := range outcomes {
+		if o.Player > o.Opponent {
+			larger = append(larger, o)
+		}
+	}
+	flags = analysis.DetectWinTrading(larger, analysis.DefaultWinTradingOptions)
+	if len(flags) != 1 || flags[0].Players[0] != "alice" || flags[0].Players[1] != "bob" || len(flags[0].Evidence) != 8 {
+		t.Fatalf("flags from the larger view = %v", flags)
+	}
+	if flags[0].Evidence[0].Detail != "alice won" {
+		t.Errorf("evidence = %v", flags[0].Evidence[0])
+	}
+}
+
+func TestDetectVolatilitySpikes(t *testing.T) {
+	point := func(day int, volatility float64) ratingutil.HistoryPoint {
+		return ratingutil.HistoryPoint{
+			At:     start.AddDate(0, 0, day),
+			Kind:   ratingutil.PeriodClosed,
+			Rating: rating.New(1500.0, 100.0, volatility),
+		}
+	}
+	series := map[string]ratingutil.RatingSeries{
+		"steady": {point(1, 0.06), point(2, 0.061), point(3, 0.059)},
+		"spiky":  {point(1, 0.06), point(2, 0.12), point(3, 0.11), point(4, 0.2)},
+	}
+	flags := analysis.DetectVolatilitySpikes(series, analysis.DefaultVolatilityOptions)
+	if len(flags) != 1 {
+		t.Fatalf("flags = %v", flags)
+	}
+	if flags[0].Players[0] != "spiky" || len(flags[0].Evidence) != 2 {
+		t.Errorf("flag = %v, evidence = %v", flags[0], flags[0].Evidence)
+	}
+	if flags[0].Score < 1.8 || flags[0].Score > 2.0 {
+		t.Errorf("score = %v", flags[0].Score)
+	}
+}
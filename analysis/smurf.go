@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mashiike/rating"
+)
+
+//SmurfOptions is the options of DetectSmurfs
+type SmurfOptions struct {
+	//MaxGames is the number of first games in which a player is considered as a new account.
+	MaxGames int
+	//PlayedBefore returns the number of games the player played before the first of the outcomes, such as Player.Games
+	//when the outcomes start to be collected. If nil, the outcomes are considered to start from the first game.
+	PlayedBefore func(player string) int
+	//MinStreak is the minimum length of the win streak.
+	MinStreak int
+	//MaxProbability is the probability of the streak under WinProb, below which the player is flagged.
+	MaxProbability float64
+}
+
+//DefaultSmurfOptions flags a streak of 5 or more wins in the first 20 games, with probability 1% or less.
+var DefaultSmurfOptions = SmurfOptions{
+	MaxGames:       20,
+	MinStreak:      5,
+	MaxProbability: 0.01,
+}
+
+//DetectSmurfs flags new accounts with an improbable win streak.
+//The probability of a streak is the product of the win probabilities against the opponents with the rating of the player
+//at the start of the streak, because the rating catches up during the streak and would explain it away.
+//So a streak against stronger opponents is less probable. The score is -log10 of the probability of the least probable streak,
+//and the evidence is the matches of the streak.
+func DetectSmurfs(outcomes []Outcome, opts SmurfOptions) []Flag {
+	var flags []Flag
+	for player, list := range byPlayer(outcomes) {
+		if opts.MaxGames > 0 {
+			played := 0
+			if opts.PlayedBefore != nil {
+				played = opts.PlayedBefore(player)
+			}
+			list = firstGames(list, opts.MaxGames-played)
+		}
+		best, bestProb := []Outcome(nil), 1.0
+		start, prob := 0, 1.0
+		for i := 0; i <= len(list); i++ {
+			if i < len(list) && list[i].Score == rating.ScoreWin {
+				prob *= list[start].Rating.WinProb(list[i].OpponentRating)
+				continue
+			}
+			if i-start >= opts.MinStreak && prob < bestProb {
+				best, bestProb = list[start:i], prob
+			}
+			start, prob = i+1, 1.0
+		}
+		if best == nil || bestProb > opts.MaxProbability {
+			continue
+		}
+		flag := Flag{
+			Kind:     Smurf,
+			Players:  []string{player},
+			Score:    -math.Log10(bestProb),
+			Evidence: make([]Evidence, 0, len(best)),
+		}
+		for _, o := range best {
+			p := best[0].Rating.WinProb(o.OpponentRating)
+			flag.Evidence = append(flag.Evidence, Evidence{
+				At:      o.At,
+				MatchID: o.MatchID,
+				Detail:  fmt.Sprintf("won against %s (%s) with win probability %0.4f", o.Opponent, o.OpponentRating.Format(rating.PlusMinusFormat), p),
+				Value:   p,
+			})
+		}
+		flags = append(flags, flag)
+	}
+	sortFlags(flags)
+	return flags
+}
+
+//firstGames returns the outcomes of the first n games, the outcomes of a multiplayer match are one game
+func firstGames(list []Outcome, n int) []Outcome {
+	games := make(map[string]bool)
+	for i, o := range list {
+		key := o.gameKey()
+		if !games[key] && len(games) == n {
+			return list[:i]
+		}
+		games[key] = true
+	}
+	return list
+}
@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/mashiike/rating/ratingutil"
+)
+
+//VolatilityOptions is the options of DetectVolatilitySpikes
+type VolatilityOptions struct {
+	//MinRatio is the ratio of the volatility to the previous point, at or above which it is a spike.
+	MinRatio float64
+}
+
+//DefaultVolatilityOptions flags the volatility increasing by 50% or more at once.
+var DefaultVolatilityOptions = VolatilityOptions{
+	MinRatio: 1.5,
+}
+
+//DetectVolatilitySpikes flags the players whose volatility jumps between consecutive points of the rating time series.
+//The series are compared per context, the score is the largest ratio and the evidence is every spike.
+func DetectVolatilitySpikes(series map[string]ratingutil.RatingSeries, opts VolatilityOptions) []Flag {
+	var flags []Flag
+	for player, s := range series {
+		var flag *Flag
+		last := make(map[string]ratingutil.HistoryPoint)
+		for _, point := range s {
+			prev, ok := last[point.Context]
+			last[point.Context] = point
+			if !ok || prev.Rating.Volatility() <= 0 {
+				continue
+			}
+			ratio := point.Rating.Volatility() / prev.Rating.Volatility()
+			if ratio < opts.MinRatio {
+				continue
+			}
+			if flag == nil {
+				flag = &Flag{Kind: VolatilitySpike, Players: []string{player}}
+			}
+			if ratio > flag.Score {
+				flag.Score = ratio
+			}
+			flag.Evidence = append(flag.Evidence, Evidence{
+				At:      point.At,
+				MatchID: point.MatchID,
+				Detail:  fmt.Sprintf("volatility %0.4f -> %0.4f", prev.Rating.Volatility(), point.Rating.Volatility()),
+				Value:   ratio,
+			})
+		}
+		if flag != nil {
+			flags = append(flags, *flag)
+		}
+	}
+	sortFlags(flags)
+	return flags
+}
@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/mashiike/rating"
+)
+
+//WinTradingOptions is the options of DetectWinTrading
+type WinTradingOptions struct {
+	//MinMatches is the minimum number of decisive matches between the pair.
+	MinMatches int
+	//MinAlternation is the minimum rate of the winner changing between consecutive matches of the pair.
+	MinAlternation float64
+}
+
+//DefaultWinTradingOptions flags a pair that played 6 or more decisive matches with the winner changing 80% of the time.
+var DefaultWinTradingOptions = WinTradingOptions{
+	MinMatches:     6,
+	MinAlternation: 0.8,
+}
+
+//DetectWinTrading flags the pairs of players repeatedly trading wins.
+//The score is the alternation rate multiplied by the share of the pair matches in the matches of the less active player,
+//and the evidence is the matches of the pair. Draws are ignored.
+func DetectWinTrading(outcomes []Outcome, opts WinTradingOptions) []Flag {
+	type pair struct{ a, b string }
+	matches := make(map[pair][]Outcome)
+	seen := make(map[string]bool)
+	total := make(map[string]int)
+	for _, o := range outcomes {
+		//the outcome is seen from the lexically smaller player, the same match may be given from both players' view
+		if o.Player > o.Opponent {
+			o = o.mirror()
+		}
+		key := fmt.Sprintf("%s\x00%s\x00%s\x00%d", o.MatchID, o.Player, o.Opponent, o.At.UnixNano())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		total[o.Player]++
+		total[o.Opponent]++
+		if o.Score == rating.ScoreDraw {
+			continue
+		}
+		p := pair{o.Player, o.Opponent}
+		matches[p] = append(matches[p], o)
+	}
+	var flags []Flag
+	for p, list := range matches {
+		if len(list) < opts.MinMatches || len(list) < 2 {
+			continue
+		}
+		sortOutcomes(list)
+		changes := 0
+		for i := 1; i < len(list); i++ {
+			if list[i].Score != list[i-1].Score {
+				changes++
+			}
+		}
+		alternation := float64(changes) / float64(len(list)-1)
+		if alternation < opts.MinAlternation {
+			continue
+		}
+		share := float64(len(list)) / float64(minInt(total[p.a], total[p.b]))
+		flag := Flag{
+			Kind:     WinTrading,
+			Players:  []string{p.a, p.b},
+			Score:    alternation * math.Min(share, 1.0),
+			Evidence: make([]Evidence, 0, len(list)),
+		}
+		for _, o := range list {
+			winner := o.Player
+			if o.Score == rating.ScoreLose {
+				winner = o.Opponent
+			}
+			flag.Evidence = append(flag.Evidence, Evidence{
+				At:      o.At,
+				MatchID: o.MatchID,
+				Detail:  fmt.Sprintf("%s won", winner),
+				Value:   o.Score,
+			})
+		}
+		flags = append(flags, flag)
+	}
+	sortFlags(flags)
+	return flags
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,151 @@
+package rating
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BatchStats is a report of the batch processing.
+type BatchStats struct {
+	Count   int
+	Workers int
+	Elapsed time.Duration
+	// Failed is the number of players whose volatility iteration did not converge, they are not changed.
+	Failed int
+}
+
+// Batch is the struct-of-arrays form of Estimated, for ending the rating period of many players at once.
+// The i-th player is represented by the i-th element of each slice.
+type Batch struct {
+	Mu          []float64
+	Phi         []float64
+	Sigma       []float64
+	Accuracy    []float64
+	Improvement []float64
+}
+
+// NewBatch is constractor of *Batch with capacity
+func NewBatch(capacity int) *Batch {
+	return &Batch{
+		Mu:          make([]float64, 0, capacity),
+		Phi:         make([]float64, 0, capacity),
+		Sigma:       make([]float64, 0, capacity),
+		Accuracy:    make([]float64, 0, capacity),
+		Improvement: make([]float64, 0, capacity),
+	}
+}
+
+// Len returns the number of players in the batch.
+func (b *Batch) Len() int {
+	return len(b.Mu)
+}
+
+// Append adds the estimated state to the batch.
+func (b *Batch) Append(e *Estimated) {
+	e.Lock()
+	defer e.Unlock()
+	b.Mu = append(b.Mu, e.Fixed.mu)
+	b.Phi = append(b.Phi, e.Fixed.phi)
+	b.Sigma = append(b.Sigma, e.Fixed.sigma)
+	b.Accuracy = append(b.Accuracy, e.Accuracy)
+	b.Improvement = append(b.Improvement, e.Improvement)
+}
+
+// Rating returns the fixed rating of the i-th player.
+func (b *Batch) Rating(i int) Rating {
+	return Rating{
+		mu:    b.Mu[i],
+		phi:   b.Phi[i],
+		sigma: b.Sigma[i],
+	}
+}
+
+// Fix ends the rating period of all players in the batch, as same as Estimated.Fix.
+// If workers is 0 or less, runtime.GOMAXPROCS(0) workers are used.
+func (b *Batch) Fix(tau float64, workers int) (BatchStats, error) {
+	return parallel(b.Len(), tau, workers, func(i int) error {
+		r, _, err := fix(b.Rating(i), b.Accuracy[i], b.Improvement[i], tau, FixOptions{})
+		if err != nil {
+			return err
+		}
+		b.Mu[i], b.Phi[i], b.Sigma[i] = r.mu, r.phi, r.sigma
+		b.Accuracy[i], b.Improvement[i] = 0.0, 0.0
+		return nil
+	})
+}
+
+// FixAll is Estimated.Fix for each estimated, processed in parallel by workers.
+// If workers is 0 or less, runtime.GOMAXPROCS(0) workers are used.
+func FixAll(estimated []*Estimated, tau float64, workers int) (BatchStats, error) {
+	return parallel(len(estimated), tau, workers, func(i int) error {
+		e := estimated[i]
+		e.Lock()
+		defer e.Unlock()
+		fixed, _, err := fix(e.Fixed, e.Accuracy, e.Improvement, tau, FixOptions{})
+		if err != nil {
+			return err
+		}
+		e.Fixed, e.Accuracy, e.Improvement = fixed, 0.0, 0.0
+		return nil
+	})
+}
+
+// FixRatings ends the rating period of the players who have not played in this period.
+// It is the same as Estimated.Fix with no matches, and the ratings are updated in place.
+func FixRatings(ratings []Rating, tau float64, workers int) (BatchStats, error) {
+	return parallel(len(ratings), tau, workers, func(i int) error {
+		var err error
+		ratings[i], _, err = fix(ratings[i], 0.0, 0.0, tau, FixOptions{})
+		return err
+	})
+}
+
+// parallel calls do for 0 to n-1 by workers.
+// If some calls fail, it returns the first error with the number of failures.
+func parallel(n int, tau float64, workers int, do func(i int) error) (BatchStats, error) {
+	start := time.Now()
+	if tau <= 0 {
+		return BatchStats{}, errTau
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+	if n == 0 {
+		return BatchStats{Elapsed: time.Since(start)}, nil
+	}
+	var (
+		wg       sync.WaitGroup
+		failed   int64
+		once     sync.Once
+		firstErr error
+	)
+	chunk := (n + workers - 1) / workers
+	for w := 0; w < workers; w++ {
+		begin, end := w*chunk, (w+1)*chunk
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(begin, end int) {
+			defer wg.Done()
+			for i := begin; i < end; i++ {
+				if err := do(i); err != nil {
+					atomic.AddInt64(&failed, 1)
+					once.Do(func() { firstErr = err })
+				}
+			}
+		}(begin, end)
+	}
+	wg.Wait()
+	return BatchStats{
+		Count:   n,
+		Workers: workers,
+		Elapsed: time.Since(start),
+		Failed:  int(failed),
+	}, firstErr
+}
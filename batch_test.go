@@ -0,0 +1,139 @@
+package rating_test
+
+import (
+	"testing"
+
+	"github.com/mashiike/rating"
+)
+
+func newBatchEstimated(n int) []*rating.Estimated {
+	opponents := []rating.Rating{
+		rating.New(1400.0, 30.0, 0.06),
+		rating.New(1550.0, 100.0, 0.06),
+		rating.New(1700.0, 300.0, 0.06),
+	}
+	estimated := make([]*rating.Estimated, 0, n)
+	for i := 0; i < n; i++ {
+		e := rating.NewEstimated(rating.New(1300.0+float64(i%400), 200.0, 0.06))
+		if i%5 != 0 {
+			for j, opponent := range opponents {
+				e.ApplyMatch(opponent, float64((i+j)%2))
+			}
+		}
+		estimated = append(estimated, e)
+	}
+	return estimated
+}
+
+func TestFixAll(t *testing.T) {
+	estimated := newBatchEstimated(1000)
+	expected := make([]rating.Rating, 0, len(estimated))
+	batch := rating.NewBatch(len(estimated))
+	for _, e := range estimated {
+		batch.Append(e)
+		clone := e.Clone()
+		if err := clone.Fix(0.5); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, clone.Fixed)
+	}
+	stats, err := rating.FixAll(estimated, 0.5, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Count != len(estimated) || stats.Workers != 4 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if _, err := batch.Fix(0.5, 0); err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range estimated {
+		if e.Fixed != expected[i] {
+			t.Errorf("FixAll[%d] got %v, expected %v", i, e.Fixed, expected[i])
+		}
+		if got := batch.Rating(i); got != expected[i] {
+			t.Errorf("Batch.Fix[%d] got %v, expected %v", i, got, expected[i])
+		}
+	}
+	if stats, err := rating.FixAll(nil, 0.5, 4); err != nil || stats.Count != 0 {
+		t.Errorf("unexpected empty batch result: %+v, %v", stats, err)
+	}
+	if _, err := rating.FixAll(estimated, 0.0, 4); err == nil {
+		t.Error("zero tau should be error")
+	}
+}
+
+func TestFixRatings(t *testing.T) {
+	ratings := []rating.Rating{rating.New(1500.0, 50.0, 0.06), rating.Default(0.06)}
+	if _, err := rating.FixRatings(ratings, 0.5, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := ratings[0].Deviation(); got <= 50.0 {
+		t.Errorf("deviation should increase: %v", got)
+	}
+	if got := ratings[1].Deviation(); got != 350.0 {
+		t.Errorf("deviation should be capped: %v", got)
+	}
+}
+
+const benchmarkPlayers = 10000
+
+// resetBatchEstimated returns the function to restore the estimates before Fix
+func resetBatchEstimated(estimated []*rating.Estimated) func() {
+	clones := make([]*rating.Estimated, 0, len(estimated))
+	for _, e := range estimated {
+		clones = append(clones, e.Clone())
+	}
+	return func() {
+		for i, e := range estimated {
+			e.Restore(clones[i])
+		}
+	}
+}
+
+func BenchmarkFixLoop(b *testing.B) {
+	estimated := newBatchEstimated(benchmarkPlayers)
+	reset := resetBatchEstimated(estimated)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		reset()
+		b.StartTimer()
+		for _, e := range estimated {
+			e.Fix(0.5)
+		}
+	}
+}
+
+func BenchmarkFixAll(b *testing.B) {
+	estimated := newBatchEstimated(benchmarkPlayers)
+	reset := resetBatchEstimated(estimated)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		reset()
+		b.StartTimer()
+		rating.FixAll(estimated, 0.5, 0)
+	}
+}
+
+func BenchmarkBatchFix(b *testing.B) {
+	estimated := newBatchEstimated(benchmarkPlayers)
+	batch := rating.NewBatch(len(estimated))
+	for _, e := range estimated {
+		batch.Append(e)
+	}
+	accuracy := append([]float64(nil), batch.Accuracy...)
+	improvement := append([]float64(nil), batch.Improvement...)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		copy(batch.Accuracy, accuracy)
+		copy(batch.Improvement, improvement)
+		b.StartTimer()
+		batch.Fix(0.5, 0)
+	}
+}
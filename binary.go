@@ -0,0 +1,77 @@
+package rating
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	estimatedBinaryVersion        byte = 1
+	estimatedBinaryVersionCompact byte = 2
+)
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+// It contains the fixed rating and the estimates during the rating period, 41 bytes.
+func (e *Estimated) MarshalBinary() ([]byte, error) {
+	e.Lock()
+	defer e.Unlock()
+	b := make([]byte, 0, 41)
+	b = append(b, estimatedBinaryVersion)
+	b = append(b, float64ToByte(e.Fixed.mu)...)
+	b = append(b, float64ToByte(e.Fixed.phi)...)
+	b = append(b, float64ToByte(e.Fixed.sigma)...)
+	b = append(b, float64ToByte(e.Accuracy)...)
+	b = append(b, float64ToByte(e.Improvement)...)
+	return b, nil
+}
+
+// MarshalBinaryCompact is the version 2 binary form for memory-constrained caches.
+// The values are stored as float32, 21 bytes. UnmarshalBinary can read it.
+func (e *Estimated) MarshalBinaryCompact() ([]byte, error) {
+	e.Lock()
+	defer e.Unlock()
+	b := make([]byte, 0, 21)
+	b = append(b, estimatedBinaryVersionCompact)
+	b = append(b, float32ToByte(e.Fixed.mu)...)
+	b = append(b, float32ToByte(e.Fixed.phi)...)
+	b = append(b, float32ToByte(e.Fixed.sigma)...)
+	b = append(b, float32ToByte(e.Accuracy)...)
+	b = append(b, float32ToByte(e.Improvement)...)
+	return b, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+// Both version 1 and the compact version 2 are accepted.
+func (e *Estimated) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("Estimated.UnmarshalBinary: no data")
+	}
+	var values [5]float64
+	switch data[0] {
+	case estimatedBinaryVersion:
+		if len(data) != 41 {
+			return errors.New("Estimated.UnmarshalBinary: invalid length")
+		}
+		for i := range values {
+			values[i] = byteToFloat64(data[1+i*8 : 9+i*8])
+		}
+	case estimatedBinaryVersionCompact:
+		if len(data) != 21 {
+			return errors.New("Estimated.UnmarshalBinary: invalid length")
+		}
+		for i := range values {
+			values[i] = byteToFloat32(data[1+i*4 : 5+i*4])
+		}
+	default:
+		return errors.New("Estimated.UnmarshalBinary: unsupported version")
+	}
+	e.Lock()
+	defer e.Unlock()
+	e.Fixed = Rating{
+		mu:    values[0],
+		phi:   values[1],
+		sigma: values[2],
+	}
+	e.Accuracy = values[3]
+	e.Improvement = values[4]
+	return nil
+}
@@ -0,0 +1,128 @@
+package rating_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mashiike/rating"
+)
+
+func TestMarshalBinaryCompact(t *testing.T) {
+	r0 := rating.New(1723.4, 87.2, 0.058)
+	enc, err := r0.MarshalBinaryCompact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enc) != 13 {
+		t.Errorf("len = %d, want 13", len(enc))
+	}
+	var r1 rating.Rating
+	if err := r1.UnmarshalBinary(enc); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(r1.Strength()-r0.Strength()) > 1e-3 || math.Abs(r1.Deviation()-r0.Deviation()) > 1e-3 {
+		t.Errorf("r1 = %v, want about %v", r1, r0)
+	}
+	if err := r1.UnmarshalBinary([]byte{3, 0, 0}); err == nil {
+		t.Error("unsupported version is accepted")
+	}
+}
+
+func TestEstimatedMarshalBinary(t *testing.T) {
+	e0 := rating.NewEstimated(rating.New(1600.0, 120.0, 0.06))
+	if err := e0.ApplyMatch(rating.New(1500.0, 80.0, 0.06), rating.ScoreWin); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := e0.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e1 := rating.NewEstimated(rating.Rating{})
+	if err := e1.UnmarshalBinary(enc); err != nil {
+		t.Fatal(err)
+	}
+	if e1.Fixed != e0.Fixed || e1.Accuracy != e0.Accuracy || e1.Improvement != e0.Improvement {
+		t.Errorf("e1 = %v, want %v", e1, e0)
+	}
+
+	compact, err := e0.MarshalBinaryCompact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compact) != 21 {
+		t.Errorf("len = %d, want 21", len(compact))
+	}
+	e2 := rating.NewEstimated(rating.Rating{})
+	if err := e2.UnmarshalBinary(compact); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(e2.Rating().Strength()-e0.Rating().Strength()) > 1e-2 {
+		t.Errorf("e2 = %v, want about %v", e2.Rating(), e0.Rating())
+	}
+	if err := e2.UnmarshalBinary(enc[:40]); err == nil {
+		t.Error("truncated data is accepted")
+	}
+}
+
+func FuzzRatingBinary(f *testing.F) {
+	f.Add(1500.0, 350.0, 0.06, false)
+	f.Add(-200.0, 0.5, 1.2, true)
+	f.Fuzz(func(t *testing.T, strength, deviation, volatility float64, compact bool) {
+		r0 := rating.New(strength, deviation, volatility)
+		marshal := r0.MarshalBinary
+		if compact {
+			marshal = r0.MarshalBinaryCompact
+		}
+		enc, err := marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r1 rating.Rating
+		if err := r1.UnmarshalBinary(enc); err != nil {
+			t.Fatal(err)
+		}
+		if compact {
+			enc2, _ := r1.MarshalBinaryCompact()
+			if string(enc) != string(enc2) {
+				t.Errorf("compact encoding is not stable: %x != %x", enc, enc2)
+			}
+			return
+		}
+		enc2, _ := r1.MarshalBinary()
+		if string(enc) != string(enc2) {
+			t.Errorf("round trip %x != %x", enc, enc2)
+		}
+	})
+}
+
+func FuzzEstimatedBinary(f *testing.F) {
+	e := rating.NewEstimated(rating.Default(0.06))
+	seed, _ := e.MarshalBinary()
+	f.Add(seed)
+	seed, _ = e.MarshalBinaryCompact()
+	f.Add(seed)
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		e0 := rating.NewEstimated(rating.Rating{})
+		if err := e0.UnmarshalBinary(data); err != nil {
+			return
+		}
+		marshal := e0.MarshalBinary
+		if data[0] == 2 {
+			marshal = e0.MarshalBinaryCompact
+		}
+		enc, err := marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		e1 := rating.NewEstimated(rating.Rating{})
+		if err := e1.UnmarshalBinary(enc); err != nil {
+			t.Fatal(err)
+		}
+		enc2, _ := e1.MarshalBinary()
+		enc1, _ := e0.MarshalBinary()
+		if string(enc1) != string(enc2) {
+			t.Errorf("round trip %x != %x", enc1, enc2)
+		}
+	})
+}
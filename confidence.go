@@ -0,0 +1,39 @@
+package rating
+
+import "math"
+
+// ZScore returns the two-sided critical value of the standard normal distribution for the confidence level,
+// such as 1.959964 for 0.95. The level must be in (0, 1), otherwise NaN.
+func ZScore(level float64) float64 {
+	if !(level > 0.0 && level < 1.0) {
+		return math.NaN()
+	}
+	return math.Sqrt2 * math.Erfinv(level)
+}
+
+// IntervalAt is like Interval, but the interval is at the confidence level, such as 0.99.
+// Interval is ±2 deviations, that is the level 0.9545.
+func (r Rating) IntervalAt(level float64) (float64, float64) {
+	return DefaultScale.IntervalAt(r, level)
+}
+
+// IsDifferentAt reports whether the strengths of r and o are significantly different at the confidence level,
+// by the two-sided z-test. IsDifferent is IsDifferentAt(o, 0.95).
+func (r Rating) IsDifferentAt(o Rating, level float64) bool {
+	return math.Abs(r.zTest(o)) > ZScore(level)
+}
+
+// PValue returns the two-sided p-value of the z-test that the strengths of r and o are the same.
+func (r Rating) PValue(o Rating) float64 {
+	return math.Erfc(math.Abs(r.zTest(o)) / math.Sqrt2)
+}
+
+// ProbStronger returns the probability that r is truly stronger than o, P(mu_r > mu_o).
+// Unlike WinProb, it is about the strengths, not the outcome of a match.
+func (r Rating) ProbStronger(o Rating) float64 {
+	return 0.5 * math.Erfc(-r.zTest(o)/math.Sqrt2)
+}
+
+func (r Rating) zTest(o Rating) float64 {
+	return (r.mu - o.mu) / math.Hypot(r.phi, o.phi)
+}
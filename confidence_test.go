@@ -0,0 +1,63 @@
+package rating_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mashiike/rating"
+)
+
+func TestZScore(t *testing.T) {
+	cases := map[float64]float64{
+		0.90:   1.644854,
+		0.95:   1.959964,
+		0.9545: 2.000000,
+		0.99:   2.575829,
+	}
+	for level, expected := range cases {
+		if got := rating.ZScore(level); math.Abs(got-expected) > 1e-4 {
+			t.Errorf("ZScore(%v) expected %v got %v", level, expected, got)
+		}
+	}
+	for _, level := range []float64{0.0, 1.0, -0.5, math.NaN()} {
+		if got := rating.ZScore(level); !math.IsNaN(got) {
+			t.Errorf("ZScore(%v) expected NaN got %v", level, got)
+		}
+	}
+}
+
+func TestIntervalAt(t *testing.T) {
+	r := rating.New(1500.0, 100.0, 0.06)
+	lower, upper := r.IntervalAt(0.99)
+	if math.Abs(lower-1242.417) > 1e-2 || math.Abs(upper-1757.583) > 1e-2 {
+		t.Errorf("unexpected IntervalAt(0.99): %v-%v", lower, upper)
+	}
+	lower, upper = r.IntervalAt(0.9545)
+	if l, u := r.Interval(); math.Abs(lower-l) > 0.1 || math.Abs(upper-u) > 0.1 {
+		t.Errorf("IntervalAt(0.9545) %v-%v is not Interval %v-%v", lower, upper, l, u)
+	}
+}
+
+func TestSignificance(t *testing.T) {
+	left := rating.New(1600.0, 50.0, 0.06)
+	right := rating.New(1500.0, 50.0, 0.06)
+	//z = 100 / hypot(50, 50) = 1.414
+	if p := left.PValue(right); math.Abs(p-0.1573) > 1e-4 {
+		t.Errorf("unexpected PValue: %v", p)
+	}
+	if p := right.PValue(left); math.Abs(p-left.PValue(right)) > 1e-12 {
+		t.Errorf("PValue is not symmetric: %v", p)
+	}
+	if p := left.ProbStronger(right); math.Abs(p-0.9214) > 1e-4 {
+		t.Errorf("unexpected ProbStronger: %v", p)
+	}
+	if p := left.ProbStronger(right) + right.ProbStronger(left); math.Abs(p-1.0) > 1e-12 {
+		t.Errorf("ProbStronger is not complementary: %v", p)
+	}
+	if !left.IsDifferentAt(right, 0.80) || left.IsDifferentAt(right, 0.90) {
+		t.Error("unexpected IsDifferentAt")
+	}
+	if left.IsDifferentAt(right, 0.95) != left.IsDifferent(right) {
+		t.Error("IsDifferentAt(0.95) is not IsDifferent")
+	}
+}
@@ -24,10 +24,10 @@ func ExampleRating() {
 	fmt.Printf("deviation : %f\n", updated.Deviation())
 	fmt.Printf("volatility: %f\n", updated.Volatility())
 	//Output:
-	//1464.0 (1161.0-1767.1 v=0.059996)
-	//strength  : 1464.050000
-	//deviation : 151.510000
-	//volatility: 0.059996
+	//1464.1 (1161.0-1767.1 v=0.059997)
+	//strength  : 1464.050670
+	//deviation : 151.516526
+	//volatility: 0.059997
 }
 
 func ExampleEstimated_Rating() {
@@ -57,16 +57,16 @@ func ExampleEstimated_Rating() {
 	fmt.Println(e.Fixed)
 	//Output:
 	//1563.6 (1212.8-1914.4 v=0.06)
-	//strength  diff : 63.560000
-	//deviation diff : -24.600000
+	//strength  diff : 63.564200
+	//deviation diff : -24.597336
 	//---
 	//1492.4 (1175.7-1809.1 v=0.06)
-	//strength  diff : -71.170000
-	//deviation diff : -17.070000
+	//strength  diff : -71.169463
+	//deviation diff : -17.070160
 	//---
-	//1464.0 (1161.0-1767.1 v=0.06)
-	//strength  diff : -28.340000
-	//deviation diff : -6.820000
+	//1464.1 (1161.0-1767.1 v=0.06)
+	//strength  diff : -28.344074
+	//deviation diff : -6.815965
 	//---
-	//1464.0 (1161.0-1767.1 v=0.059996)
+	//1464.1 (1161.0-1767.1 v=0.059997)
 }
@@ -2,6 +2,8 @@ package rating
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"strconv"
 	"unicode"
 )
@@ -14,6 +16,17 @@ const (
 	stdDeviation
 	stdError
 	stdVolatility
+	stdMu
+	stdWinProb
+	stdPValue
+	stdProbStronger
+	stdPercent
+
+	// precision+1 and the confidence level in basis points+1 of the directive are stored in the upper bits, as same as time.Time
+	stdPrecShift  = 8
+	stdMask       = 1<<stdPrecShift - 1
+	stdPrecMax    = 1<<(stdLevelShift-stdPrecShift) - 2
+	stdLevelShift = 14
 )
 
 //Rating Format examples
@@ -27,6 +40,23 @@ const (
 //  LowerStrength    800.0
 //  UpperStrength    2200.0
 //  95%PlusMinusDiff 700.0
+//
+//In addition to the examples, the layout can contain directives as follows.
+//  %S  Strength
+//  %D  Deviation
+//  %V  Volatility
+//  %L  LowerStrength
+//  %U  UpperStrength
+//  %E  95%PlusMinusDiff
+//  %N  Strength on the Glicko-2 scale (mu)
+//  %W  Winning probability against the opponent, see FormatVersus
+//  %P  p-value of the difference from the opponent, see Rating.PValue
+//  %G  probability of being stronger than the opponent, see Rating.ProbStronger
+//  %%  a literal %
+//The precision can follow the directive like %S{.2}, and the default is the same as the examples.
+//for example, "Rating: %S{.0}" is formatted as "Rating: 1500".
+//%L, %U and %E take the confidence level in percent like %L{@99} or %L{.0@99}, see Rating.IntervalAt.
+//Without the level, they are ±2 deviations as Rating.Interval.
 const (
 	StrengthOnlyFormat = "1500.0"
 	WithRangeFormat    = "1500.0 (800.0-2200.0)"
@@ -45,6 +75,20 @@ type ParseError struct {
 	LayoutElem string
 	ValueElem  string
 	Message    string
+	// Column is the 1-based position in Value where the problem is found.
+	// It is 0 when the problem is not at a position, such as a missing field.
+	Column int
+}
+
+func newParseError(layout, value, layoutElem, valueElem, message string) *ParseError {
+	return &ParseError{
+		Layout:     layout,
+		Value:      value,
+		LayoutElem: layoutElem,
+		ValueElem:  valueElem,
+		Message:    message,
+		Column:     len(value) - len(valueElem) + 1,
+	}
 }
 
 func quote(s string) string {
@@ -53,36 +97,79 @@ func quote(s string) string {
 
 // Error returns the string representation of a ParseError.
 func (e *ParseError) Error() string {
+	column := ""
+	if e.Column > 0 {
+		column = " at column " + strconv.Itoa(e.Column)
+	}
 	if e.Message == "" {
 		return "parsing rating " +
 			quote(e.Value) + " as " +
 			quote(e.Layout) + ": cannot parse " +
 			quote(e.ValueElem) + " as " +
-			quote(e.LayoutElem)
+			quote(e.LayoutElem) + column
 	}
 	return "parsing rating " +
-		quote(e.Value) + e.Message
+		quote(e.Value) + e.Message + column
+}
+
+// ParseMode is the strictness of parsing.
+type ParseMode int
+
+// ParseMode constants
+const (
+	// ParseDefault is the mode of Parse.
+	// Missing deviation falls back to InitialDeviation and missing volatility to the default volatility.
+	ParseDefault ParseMode = iota
+	// ParseStrict requires strength, deviation (or the range) and volatility,
+	// and rejects out-of-range values such as negative deviation or volatility, or an upper bound below the lower.
+	ParseStrict
+	// ParseLenient accepts signed, scientific and comma decimal numbers like -1.5e3 or 1500,5.
+	// A comma is a decimal mark only when the layout does not continue with a comma.
+	ParseLenient
+)
+
+// ParseOptions is the options of ParseWithOptions.
+type ParseOptions struct {
+	Mode ParseMode
+	// DefaultVolatility is used when the layout has no volatility, if zero 0.06.
+	DefaultVolatility float64
+	// Scale of the values, if zero value DefaultScale.
+	Scale Scale
+}
+
+//ParseWithOptions parses a formatted string with the options.
+func ParseWithOptions(layout, value string, opts ParseOptions) (Rating, error) {
+	if opts.DefaultVolatility == 0.0 {
+		opts.DefaultVolatility = 0.06
+	}
+	if opts.Scale == (Scale{}) {
+		opts.Scale = DefaultScale
+	}
+	return parse(opts.Scale, layout, value, opts)
 }
 
 //Parse parses a formatted string and returns the rating value it represents.
 //if not include volatility in layout, volatility set 0.06
 func Parse(layout, value string) (Rating, error) {
-	return parse(layout, value, 0.06)
+	return parse(DefaultScale, layout, value, ParseOptions{DefaultVolatility: 0.06})
 }
 
 //ParseWithVolatility is parse a formatted string with default volatility.
 func ParseWithVolatility(layout, value string, volatility float64) (Rating, error) {
-	return parse(layout, value, volatility)
+	return parse(DefaultScale, layout, value, ParseOptions{DefaultVolatility: volatility})
 }
 
-func parse(layout, value string, defaultVolatility float64) (Rating, error) {
+func parse(scale Scale, layout, value string, opts ParseOptions) (Rating, error) {
 	alayout, avalue := layout, value
 	var (
-		strength   = float64(centerValue)
+		strength   = scale.Center
 		deviation  float64
-		volatility = defaultVolatility
+		volatility = opts.DefaultVolatility
 		lower      float64
 		upper      float64
+		seen       = make(map[int]bool)
+		upperElem  string
+		rangeZ     = 2.0
 	)
 
 	for {
@@ -90,44 +177,104 @@ func parse(layout, value string, defaultVolatility float64) (Rating, error) {
 		prefix, std, suffix := nextStdChunk(layout)
 		value, err = skip(value, prefix)
 		if err != nil {
-			return Rating{}, &ParseError{alayout, avalue, prefix, value, ""}
+			return Rating{}, newParseError(alayout, avalue, prefix, value, "")
 		}
 		if std == 0 {
 			if len(value) != 0 {
-				return Rating{}, &ParseError{alayout, avalue, "", value, ": extra text: " + value}
+				return Rating{}, newParseError(alayout, avalue, "", value, ": extra text: "+value)
 			}
 			break
 		}
 		layout = suffix
+		if std == stdPercent {
+			value, err = skip(value, "%")
+			if err != nil {
+				return Rating{}, newParseError(alayout, avalue, "%%", value, "")
+			}
+			continue
+		}
 		var fval float64
-		fval, value, err = extractFloat(value)
+		elem := value
+		if opts.Mode == ParseLenient {
+			next, _, _ := nextStdChunk(suffix)
+			fval, value, err = extractLenientFloat(value, len(next) == 0 || next[0] != ',')
+		} else if opts.Mode == ParseStrict {
+			fval, value, err = extractSignedFloat(value)
+		} else {
+			fval, value, err = extractFloat(value)
+		}
 		if err != nil {
-			return Rating{}, &ParseError{alayout, avalue, prefix, value, ""}
+			return Rating{}, newParseError(alayout, avalue, prefix, elem, "")
 		}
-		switch std {
+		if math.IsNaN(fval) || math.IsInf(fval, 0) {
+			return Rating{}, newParseError(alayout, avalue, prefix, elem, ": not a finite number")
+		}
+		seen[std&stdMask] = true
+		if opts.Mode == ParseStrict {
+			switch std & stdMask {
+			case stdDeviation, stdError, stdVolatility:
+				if fval < 0.0 {
+					return Rating{}, newParseError(alayout, avalue, prefix, elem, ": negative value")
+				}
+			}
+		}
+		switch std & stdMask {
 		case stdStrength:
 			strength = fval
 		case stdLower:
 			lower = fval
+			rangeZ = zOf(std)
 		case stdUpper:
 			upper = fval
+			upperElem = elem
+			rangeZ = zOf(std)
 		case stdDeviation:
 			deviation = fval
 		case stdError:
-			deviation = fval / 2.0
+			deviation = fval / zOf(std)
 		case stdVolatility:
 			volatility = fval
+		case stdMu:
+			strength = fval*scale.factor() + scale.Center
+		}
+	}
+	if opts.Mode == ParseStrict {
+		if err := checkStrict(seen, lower, upper); err == errUpperBelowLower {
+			return Rating{}, newParseError(alayout, avalue, "", upperElem, ": "+err.Error())
+		} else if err != nil {
+			parseErr := newParseError(alayout, avalue, "", "", ": "+err.Error())
+			parseErr.Column = 0
+			return Rating{}, parseErr
 		}
 	}
 	if deviation == 0.0 {
-		deviation = (upper - lower) / 4.0
+		deviation = (upper - lower) / (2.0 * rangeZ)
 		if deviation == 0.0 {
-			deviation = InitialDeviation
+			deviation = scale.InitialDeviation()
 		}
 	}
-	return New(strength, deviation, volatility), nil
+	return scale.New(strength, deviation, volatility), nil
 }
 
+func checkStrict(seen map[int]bool, lower, upper float64) error {
+	hasRange := seen[stdLower] && seen[stdUpper]
+	switch {
+	case !seen[stdStrength] && !seen[stdMu] && !hasRange:
+		return errors.New("strength is required")
+	case !seen[stdDeviation] && !seen[stdError] && !hasRange:
+		return errors.New("deviation is required")
+	case !seen[stdVolatility]:
+		return errors.New("volatility is required")
+	case seen[stdLower] != seen[stdUpper]:
+		return errors.New("both lower and upper are required")
+	case hasRange && upper < lower:
+		return errUpperBelowLower
+	}
+	return nil
+}
+
+var errUpperBelowLower = errors.New("upper is below lower")
+
 type formatElem struct {
 	face     string
 	stdChunk int
@@ -154,8 +301,97 @@ var elems = []formatElem{
 	{"800.0", stdLower},
 }
 
+var directives = map[byte]int{
+	'S': stdStrength,
+	'D': stdDeviation,
+	'V': stdVolatility,
+	'L': stdLower,
+	'U': stdUpper,
+	'E': stdError,
+	'N': stdMu,
+	'W': stdWinProb,
+	'P': stdPValue,
+	'G': stdProbStronger,
+	'%': stdPercent,
+}
+
+// directive returns the directive std at layout[i], the length of it.
+func directive(i int, layout string) (std int, length int) {
+	if layout[i] != '%' || i+1 >= len(layout) {
+		return 0, 0
+	}
+	std, ok := directives[layout[i+1]]
+	if !ok {
+		return 0, 0
+	}
+	length = 2
+	if std == stdPercent {
+		return std, length
+	}
+	// options {.n}, {@level} or {.n@level}
+	if opts, n := directiveOptions(std, layout[i+2:]); n > 0 {
+		std |= opts
+		length += n
+	}
+	return std, length
+}
+
+// directiveOptions returns the option bits in braces at the head of rest, and the length of it.
+// The length is zero if rest does not start with valid options.
+func directiveOptions(std int, rest string) (opts int, length int) {
+	if len(rest) < 3 || rest[0] != '{' {
+		return 0, 0
+	}
+	end := 1
+	for end < len(rest) && rest[end] != '}' {
+		end++
+	}
+	if end == len(rest) {
+		return 0, 0
+	}
+	body := rest[1:end]
+	if body[0] == '.' {
+		j := 1
+		for j < len(body) && unicode.IsDigit(rune(body[j])) {
+			j++
+		}
+		if j == 1 {
+			return 0, 0
+		}
+		prec, err := strconv.Atoi(body[1:j])
+		if err != nil || prec > stdPrecMax {
+			return 0, 0
+		}
+		opts |= (prec + 1) << stdPrecShift
+		body = body[j:]
+	}
+	if body != "" {
+		if body[0] != '@' {
+			return 0, 0
+		}
+		switch std {
+		case stdLower, stdUpper, stdError:
+		default:
+			return 0, 0
+		}
+		percent, err := strconv.ParseFloat(body[1:], 64)
+		if err != nil || body[1] == '+' || body[1] == '-' {
+			return 0, 0
+		}
+		bp := int(math.Round(percent * 100.0))
+		if bp <= 0 || bp >= 10000 {
+			return 0, 0
+		}
+		opts |= (bp + 1) << stdLevelShift
+	}
+	return opts, end + 1
+}
+
 func nextStdChunk(layout string) (prefix string, std int, suffix string) {
 	for i := 0; i < len(layout); i++ {
+		if std, length := directive(i, layout); std != 0 {
+			return layout[0:i], std, layout[i+length:]
+		}
 		for _, elem := range elems {
 			if c := int(layout[i]); c == elem.first() {
 				if elem.isMatch(i, layout) {
@@ -168,6 +404,30 @@ func nextStdChunk(layout string) (prefix string, std int, suffix string) {
 	return layout, 0, ""
 }
 
+// precision returns the precision of the std chunk
+func precision(std int, defaultPrec int) int {
+	if prec := (std >> stdPrecShift) & (stdPrecMax + 1); prec > 0 {
+		return prec - 1
+	}
+	return defaultPrec
+}
+
+// level returns the confidence level of the std chunk, such as 0.99
+func level(std int) (float64, bool) {
+	if bp := std >> stdLevelShift; bp > 0 {
+		return float64(bp-1) / 10000.0, true
+	}
+	return 0.0, false
+}
+
+// zOf returns the critical value of the std chunk, 2.0 without the level as Interval
+func zOf(std int) float64 {
+	if l, ok := level(std); ok {
+		return ZScore(l)
+	}
+	return 2.0
+}
+
 func cutspace(s string) string {
 	for len(s) > 0 && s[0] == ' ' {
 		s = s[1:]
@@ -210,10 +470,71 @@ func extractFloat(value string) (float64, string, error) {
 	return fval, value[i:], err
 }
 
+// extractSignedFloat is like extractFloat with a sign, so that ParseStrict can reject a negative value.
+func extractSignedFloat(value string) (float64, string, error) {
+	if len(value) == 0 || (value[0] != '+' && value[0] != '-') {
+		return extractFloat(value)
+	}
+	fval, rest, err := extractFloat(value[1:])
+	if value[0] == '-' {
+		fval = -fval
+	}
+	return fval, rest, err
+}
+
+// extractLenientFloat accepts a sign, an exponent and a comma as the decimal mark if commaDecimal.
+func extractLenientFloat(value string, commaDecimal bool) (float64, string, error) {
+	i := 0
+	if i < len(value) && (value[i] == '+' || value[i] == '-') {
+		i++
+	}
+	isPointed, hasDigits := false, false
+	for ; i < len(value); i++ {
+		c := value[i]
+		if !isPointed && (c == '.' || (commaDecimal && c == ',')) {
+			isPointed = true
+			continue
+		}
+		if !unicode.IsDigit(rune(c)) {
+			break
+		}
+		hasDigits = true
+	}
+	if hasDigits && i < len(value) && (value[i] == 'e' || value[i] == 'E') {
+		j := i + 1
+		if j < len(value) && (value[j] == '+' || value[j] == '-') {
+			j++
+		}
+		k := j
+		for k < len(value) && unicode.IsDigit(rune(value[k])) {
+			k++
+		}
+		if k > j {
+			i = k
+		}
+	}
+	num := []byte(value[0:i])
+	for n, c := range num {
+		if c == ',' {
+			num[n] = '.'
+		}
+	}
+	fval, err := strconv.ParseFloat(string(num), 64)
+	return fval, value[i:], err
+}
+
 // AppendFormat is like Format but appends the textual
 // as same as time.Time
 func (r Rating) AppendFormat(b []byte, layout string) []byte {
-	lower, upper := r.Interval()
+	return appendFormat(DefaultScale, r, nil, b, layout)
+}
+
+// AppendFormatVersus is like FormatVersus but appends the textual
+func (r Rating) AppendFormatVersus(b []byte, layout string, opponent Rating) []byte {
+	return appendFormat(DefaultScale, r, &opponent, b, layout)
+}
+
+func appendFormat(scale Scale, r Rating, opponent *Rating, b []byte, layout string) []byte {
 	for layout != "" {
 		prefix, std, suffix := nextStdChunk(layout)
 		if prefix != "" {
@@ -223,29 +544,85 @@ func (r Rating) AppendFormat(b []byte, layout string) []byte {
 			break
 		}
 		layout = suffix
-		var value string
-		switch std {
+		switch std & stdMask {
 		case stdStrength:
-			value = strconv.FormatFloat(r.Strength(), 'f', 1, 64)
+			b = strconv.AppendFloat(b, scale.Strength(r), 'f', precision(std, 1), 64)
 		case stdLower:
-			value = strconv.FormatFloat(lower, 'f', 1, 64)
+			lower, _ := interval(scale, r, std)
+			b = strconv.AppendFloat(b, lower, 'f', precision(std, 1), 64)
 		case stdUpper:
-			value = strconv.FormatFloat(upper, 'f', 1, 64)
+			_, upper := interval(scale, r, std)
+			b = strconv.AppendFloat(b, upper, 'f', precision(std, 1), 64)
 		case stdDeviation:
-			value = strconv.FormatFloat(r.Deviation(), 'f', 1, 64)
+			b = strconv.AppendFloat(b, scale.Deviation(r), 'f', precision(std, 1), 64)
 		case stdError:
-			value = strconv.FormatFloat(r.Deviation()*2.0, 'f', 1, 64)
+			if _, ok := level(std); ok {
+				b = strconv.AppendFloat(b, zOf(std)*scale.Deviation(r), 'f', precision(std, 1), 64)
+				continue
+			}
+			b = strconv.AppendFloat(b, scale.Deviation(r)*2.0, 'f', precision(std, 1), 64)
 		case stdVolatility:
-			value = strconv.FormatFloat(r.Volatility(), 'f', -1, 64)
+			b = appendVolatility(b, r.Volatility(), precision(std, -1))
+		case stdMu:
+			b = strconv.AppendFloat(b, r.mu, 'f', precision(std, 4), 64)
+		case stdWinProb:
+			if opponent == nil {
+				b = append(b, "%!W(NOOPPONENT)"...)
+				continue
+			}
+			b = strconv.AppendFloat(b, r.WinProb(*opponent), 'f', precision(std, 2), 64)
+		case stdPValue:
+			if opponent == nil {
+				b = append(b, "%!P(NOOPPONENT)"...)
+				continue
+			}
+			b = strconv.AppendFloat(b, r.PValue(*opponent), 'f', precision(std, 4), 64)
+		case stdProbStronger:
+			if opponent == nil {
+				b = append(b, "%!G(NOOPPONENT)"...)
+				continue
+			}
+			b = strconv.AppendFloat(b, r.ProbStronger(*opponent), 'f', precision(std, 2), 64)
+		case stdPercent:
+			b = append(b, '%')
 		}
-		b = append(b, value...)
 	}
 	return b
 }
 
+// appendVolatility appends the volatility with the precision,
+// or without trailing zeros up to volatilityDigits if the precision is negative, such as 0.06.
+func appendVolatility(b []byte, volatility float64, prec int) []byte {
+	if prec >= 0 {
+		return strconv.AppendFloat(b, volatility, 'f', prec, 64)
+	}
+	shift := math.Pow(10, volatilityDigits)
+	return strconv.AppendFloat(b, math.Round(volatility*shift)/shift, 'f', -1, 64)
+}
+
+// volatilityDigits is the maximum decimal places of the volatility without the precision
+const volatilityDigits = 6
+
+// interval returns the interval of the std chunk, Interval without the level
+func interval(scale Scale, r Rating, std int) (float64, float64) {
+	if l, ok := level(std); ok {
+		return scale.IntervalAt(r, l)
+	}
+	return scale.Interval(r)
+}
+
 // Format returns a textual representation of the rating value formatted
 // as same as time.Time
 func (r Rating) Format(layout string) string {
+	return r.format(layout, nil)
+}
+
+// FormatVersus is like Format, and %W, %P and %G in the layout are against the opponent.
+func (r Rating) FormatVersus(layout string, opponent Rating) string {
+	return r.format(layout, &opponent)
+}
+
+func (r Rating) format(layout string, opponent *Rating) string {
 	const bufSize = 64
 	var b []byte
 	max := len(layout) + 10
@@ -255,7 +632,7 @@ func (r Rating) Format(layout string) string {
 	} else {
 		b = make([]byte, 0, max)
 	}
-	b = r.AppendFormat(b, layout)
+	b = appendFormat(DefaultScale, r, opponent, b, layout)
 	return string(b)
 }
 
@@ -264,3 +641,61 @@ func (r Rating) Format(layout string) string {
 func (r Rating) String() string {
 	return r.Format(DetailFormat)
 }
+
+// Formatter wraps Rating to implement fmt.Formatter.
+// Rating itself can not implement it, because Rating.Format is the layout based method.
+//   %v       DetailFormat, as same as String
+//   %+v      DetailFormat with the values on the Glicko-2 scale
+//   %#v      Go syntax
+//   %s, %q   as same as %v, and quoted
+//   %f %e %g strength, with the flags, width and precision. for example, %.1f is 1500.0
+type Formatter struct {
+	Rating
+}
+
+// Fmt returns the fmt.Formatter of the rating, for example fmt.Printf("%.1f", r.Fmt())
+func (r Rating) Fmt() Formatter {
+	return Formatter{r}
+}
+
+// Format implements fmt.Formatter
+func (f Formatter) Format(s fmt.State, verb rune) {
+	r := f.Rating
+	switch verb {
+	case 'v':
+		switch {
+		case s.Flag('#'):
+			fmt.Fprintf(s, "rating.NewGlicko2(%#v, %#v, %#v)", r.mu, r.phi, r.sigma)
+		case s.Flag('+'):
+			fmt.Fprintf(s, "%s mu=%g phi=%g sigma=%g", r.String(), r.mu, r.phi, r.sigma)
+		default:
+			fmt.Fprint(s, r.String())
+		}
+	case 's':
+		fmt.Fprint(s, r.String())
+	case 'q':
+		fmt.Fprint(s, strconv.Quote(r.String()))
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		fmt.Fprintf(s, formatDirective(s, verb), r.Strength())
+	default:
+		fmt.Fprintf(s, "%%!%c(rating.Rating=%s)", verb, r.String())
+	}
+}
+
+// formatDirective rebuilds the fmt directive from the state
+func formatDirective(s fmt.State, verb rune) string {
+	b := []byte{'%'}
+	for _, flag := range "+-# 0" {
+		if s.Flag(int(flag)) {
+			b = append(b, byte(flag))
+		}
+	}
+	if width, ok := s.Width(); ok {
+		b = strconv.AppendInt(b, int64(width), 10)
+	}
+	if prec, ok := s.Precision(); ok {
+		b = append(b, '.')
+		b = strconv.AppendInt(b, int64(prec), 10)
+	}
+	return string(append(b, string(verb)...))
+}
@@ -1,6 +1,9 @@
 package rating_test
 
 import (
+	"fmt"
+	"math"
+	"strings"
 	"testing"
 
 	"github.com/mashiike/rating"
@@ -59,3 +62,151 @@ func TestFormat(t *testing.T) {
 		}
 	}
 }
+
+var directiveFormatTests = []FormatTest{
+	{"Strength", "Rating: %S{.0}", "Rating: 1500"},
+	{"Precision", "%S{.2} ± %E{.3}", "1500.00 ± 700.000"},
+	{"All", "%S %D %V %L %U %E %N", "1500.0 350.0 0.06 800.0 2200.0 700.0 0.0000"},
+	{"Escape", "%S (%%)", "1500.0 (%)"},
+	{"Mixed", "%S{.0} 800.0-2200.0", "1500 800.0-2200.0"},
+	{"Unknown", "%X %S{.x}", "%X 1500.0{.x}"},
+	{"NoOpponent", "%W", "%!W(NOOPPONENT)"},
+	{"Level", "%L{.1@99}-%U{.1@99} ±%E{.0@99}", "598.5-2401.5 ±902"},
+	{"LevelOnly", "%L{@95}", "814.0"},
+	{"LevelNotInterval", "%S{@99}", "1500.0{@99}"},
+	{"BadLevel", "%L{@100} %E{@x}", "800.0{@100} 700.0{@x}"},
+	{"NoOpponentTest", "%P %G", "%!P(NOOPPONENT) %!G(NOOPPONENT)"},
+}
+
+func TestFormatDirective(t *testing.T) {
+	player := rating.Default(0.06)
+	for _, test := range directiveFormatTests {
+		result := player.Format(test.format)
+		if result != test.result {
+			t.Errorf("%s expected %q got %q", test.name, test.result, result)
+		}
+	}
+	got := rating.New(1523.456789, 87.654321, 0.06).Format("%S{.4} %D{.5} %E{.4}")
+	if got != "1523.4568 87.65432 175.3086" {
+		t.Errorf("Format with precision got %q", got)
+	}
+	got = rating.New(1700.0, 0.0, 0.06).FormatVersus("P(win)=%W{.3}", rating.New(1500.0, 0.0, 0.06))
+	if got != "P(win)=0.760" {
+		t.Errorf("FormatVersus got %q", got)
+	}
+	got = rating.New(1600.0, 50.0, 0.06).FormatVersus("p=%P P(stronger)=%G{.3}", rating.New(1500.0, 50.0, 0.06))
+	if got != "p=0.1573 P(stronger)=0.921" {
+		t.Errorf("FormatVersus got %q", got)
+	}
+}
+
+func TestParseDirectiveLevel(t *testing.T) {
+	for _, c := range []struct{ layout, value string }{
+		{"%S p-%E{@99}", "1500 p-901.54"},
+		{"%L{@99}-%U{@99}", "598.46-2401.54"},
+	} {
+		got, err := rating.Parse(c.layout, c.value)
+		if err != nil {
+			t.Fatalf("%s error: %v", c.layout, err)
+		}
+		if math.Abs(got.Deviation()-350.0) > 0.1 || math.Abs(got.Strength()-1500.0) > 0.1 {
+			t.Errorf("%s unexpected: got is %v", c.layout, got)
+		}
+	}
+}
+
+func TestParseDirective(t *testing.T) {
+	cases := []ParseTest{
+		{"Strength", "Rating: %S{.0}", "Rating: 1320", rating.New(1320.0, 350.0, 0.06)},
+		{"Detail", "%S (%%) d=%D v=%V", "1320 (%) d=75 v=0.25", rating.New(1320.0, 75.0, 0.25)},
+		{"Range", "%L-%U", "1170-1470", rating.New(1500.0, 75.0, 0.06)},
+		{"Mu", "%N,%E", "0.0,150", rating.New(1500.0, 75.0, 0.06)},
+	}
+	for _, test := range cases {
+		got, err := rating.Parse(test.format, test.value)
+		if err != nil {
+			t.Errorf("%s error: %v", test.name, err)
+		}
+		if got != test.expected {
+			t.Errorf("%s unexpected: got is %v", test.name, got)
+		}
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	r := rating.Default(0.06)
+	cases := []struct {
+		format   string
+		expected string
+	}{
+		{"%v", "1500.0 (800.0-2200.0 v=0.06)"},
+		{"%s", "1500.0 (800.0-2200.0 v=0.06)"},
+		{"%q", `"1500.0 (800.0-2200.0 v=0.06)"`},
+		{"%+v", "1500.0 (800.0-2200.0 v=0.06) mu=0 phi=2.014761872416068 sigma=0.06"},
+		{"%.1f", "1500.0"},
+		{"%8.0f|", "    1500|"},
+		{"%d", "%!d(rating.Rating=1500.0 (800.0-2200.0 v=0.06))"},
+	}
+	for _, c := range cases {
+		if got := fmt.Sprintf(c.format, r.Fmt()); got != c.expected {
+			t.Errorf("%s expected %q got %q", c.format, c.expected, got)
+		}
+	}
+	if got := fmt.Sprintf("%#v", r.Fmt()); got != "rating.NewGlicko2(0, 2.014761872416068, 0.06)" {
+		t.Errorf("%%#v got %q", got)
+	}
+}
+
+func TestParseWithOptions(t *testing.T) {
+	strict := rating.ParseOptions{Mode: rating.ParseStrict}
+	lenient := rating.ParseOptions{Mode: rating.ParseLenient}
+	centered := rating.Scale{Center: 25.0}
+	cases := []struct {
+		name     string
+		format   string
+		value    string
+		opts     rating.ParseOptions
+		expected rating.Rating
+		column   int
+		message  string
+	}{
+		{"StrictOK", rating.DefaultFormat, "1320p-150.0v=0.25", strict, rating.New(1320.0, 75.0, 0.25), 0, ""},
+		{"StrictRange", rating.DetailFormat, "1320 (1170-1470 v=0.25)", strict, rating.New(1320.0, 75.0, 0.25), 0, ""},
+		{"StrictNoDeviation", rating.StrengthOnlyFormat, "1320", strict, rating.Rating{}, 0, "deviation is required"},
+		{"StrictNoVolatility", rating.PlusMinusFormat, "1320p-150.0", strict, rating.Rating{}, 0, "volatility is required"},
+		{"StrictUpperBelowLower", "%S (%L-%U) %V", "1320 (1470-1170) 0.06", strict, rating.Rating{}, 12, "upper is below lower"},
+		{"StrictNegative", "%S %D{.1} %V", "1320 75.0 -0.06", strict, rating.Rating{}, 11, "negative value"},
+		{"StrictNegativeDeviation", "%S %D %V", "1320 -350 0.06", strict, rating.Rating{}, 6, "negative value"},
+		{"StrictSignedStrength", "%S %D %V", "-1320 350 0.06", strict, rating.New(-1320.0, 350.0, 0.06), 0, ""},
+		{"DefaultSigned", rating.StrengthOnlyFormat, "-1320", rating.ParseOptions{}, rating.Rating{}, 1, ""},
+		{"LenientSigned", rating.StrengthOnlyFormat, "-1320", lenient, rating.New(-1320.0, 350.0, 0.06), 0, ""},
+		{"LenientScientific", rating.CSVFormat, "1.32e3,7.5E+1,6e-2", lenient, rating.New(1320.0, 75.0, 0.06), 0, ""},
+		{"LenientComma", "%S p-%E", "+1320,5 p-150,0", lenient, rating.New(1320.5, 75.0, 0.06), 0, ""},
+		{"LenientOverflow", rating.StrengthOnlyFormat, "1e999", lenient, rating.Rating{}, 1, ""},
+		{"CustomCenter", rating.DefaultFormat, "35p-150.0v=0.25", rating.ParseOptions{Scale: centered}, centered.New(35.0, 75.0, 0.25), 0, ""},
+		{"ExtraText", rating.StrengthOnlyFormat, "1320 pts", rating.ParseOptions{}, rating.Rating{}, 5, ""},
+	}
+	for _, c := range cases {
+		got, err := rating.ParseWithOptions(c.format, c.value, c.opts)
+		if c.column == 0 && c.message == "" {
+			if err != nil {
+				t.Errorf("%s error: %v", c.name, err)
+			}
+			if got != c.expected {
+				t.Errorf("%s unexpected: got is %v", c.name, got)
+			}
+			continue
+		}
+		parseErr, ok := err.(*rating.ParseError)
+		if !ok {
+			t.Errorf("%s expected ParseError, got %v", c.name, err)
+			continue
+		}
+		if parseErr.Column != c.column {
+			t.Errorf("%s expected column %d, got %v", c.name, c.column, parseErr)
+		}
+		if !strings.Contains(parseErr.Error(), c.message) {
+			t.Errorf("%s expected %q, got %v", c.name, c.message, parseErr)
+		}
+	}
+}
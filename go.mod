@@ -1,5 +1,5 @@
 module github.com/mashiike/rating
 
-go 1.15
+go 1.18
 
 require github.com/pkg/errors v0.9.1
@@ -0,0 +1,135 @@
+package rating
+
+import (
+	"encoding/json"
+	"math"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// JSONForm is the JSON representation of Rating
+type JSONForm int32
+
+// JSONForm values
+const (
+	// JSONString is a quoted string in Default format, such as "1500.0p-350.0v=0.06". It is the default.
+	JSONString JSONForm = iota
+	// JSONStructured is an object with the values on both the display scale and the Glicko-2 scale.
+	// It is lossless.
+	JSONStructured
+)
+
+var jsonForm int32
+
+// SetJSONForm sets the JSON representation of Rating for the whole program.
+// To select the representation per value, use StructuredRating instead.
+func SetJSONForm(form JSONForm) {
+	atomic.StoreInt32(&jsonForm, int32(form))
+}
+
+// JSONFormOf returns the JSON representation of Rating set by SetJSONForm
+func JSONFormOf() JSONForm {
+	return JSONForm(atomic.LoadInt32(&jsonForm))
+}
+
+// StructuredRating is a Rating which is always encoded as a structured JSON object, such as
+//
+//	{"strength":1500,"deviation":350,"volatility":0.06,"mu":0,"phi":2.014761872416068,"sigma":0.06}
+//
+// The Glicko-2 values are used in decoding when present, otherwise the display values.
+type StructuredRating struct {
+	Rating
+}
+
+type structuredRating struct {
+	Strength   *float64 `json:"strength,omitempty"`
+	Deviation  *float64 `json:"deviation,omitempty"`
+	Volatility *float64 `json:"volatility,omitempty"`
+	Mu         *float64 `json:"mu,omitempty"`
+	Phi        *float64 `json:"phi,omitempty"`
+	Sigma      *float64 `json:"sigma,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (r StructuredRating) MarshalJSON() ([]byte, error) {
+	strength, deviation, volatility := r.Strength(), r.Deviation(), r.Volatility()
+	mu, phi, sigma := r.mu, r.phi, r.sigma
+	return json.Marshal(structuredRating{
+		Strength:   &strength,
+		Deviation:  &deviation,
+		Volatility: &volatility,
+		Mu:         &mu,
+		Phi:        &phi,
+		Sigma:      &sigma,
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// The quoted string form is also accepted.
+func (r *StructuredRating) UnmarshalJSON(data []byte) error {
+	return r.Rating.UnmarshalJSON(data)
+}
+
+func (r *Rating) unmarshalStructured(data []byte) error {
+	var v structuredRating
+	if err := json.Unmarshal(data, &v); err != nil {
+		return errors.Wrap(err, "Rating.UnmarshalJSON")
+	}
+	var decoded Rating
+	switch {
+	case v.Mu != nil && v.Phi != nil && v.Sigma != nil:
+		decoded = NewGlicko2(*v.Mu, *v.Phi, *v.Sigma)
+	case v.Strength != nil && v.Deviation != nil && v.Volatility != nil:
+		decoded = New(*v.Strength, *v.Deviation, *v.Volatility)
+	default:
+		return errors.New("Rating.UnmarshalJSON: strength, deviation and volatility or mu, phi and sigma are required")
+	}
+	if !isFinite(decoded.mu) || !isFinite(decoded.phi) || !isFinite(decoded.sigma) {
+		return errors.New("Rating.UnmarshalJSON: value is not finite")
+	}
+	*r = decoded
+	return nil
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+type estimatedJSON struct {
+	Accuracy    float64          `json:"accuracy"`
+	Improvement float64          `json:"improvement"`
+	Fixed       StructuredRating `json:"fixed"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The fixed rating is a structured object, so the encoding is lossless.
+// It has a pointer receiver so that the lock is not copied, marshal *Estimated.
+func (e *Estimated) MarshalJSON() ([]byte, error) {
+	e.Lock()
+	v := estimatedJSON{
+		Accuracy:    e.Accuracy,
+		Improvement: e.Improvement,
+		Fixed:       StructuredRating{e.Fixed},
+	}
+	e.Unlock()
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// The fixed rating may be a structured object or a quoted string in Default format.
+func (e *Estimated) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var v estimatedJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return errors.Wrap(err, "Estimated.UnmarshalJSON")
+	}
+	e.Lock()
+	defer e.Unlock()
+	e.Accuracy = v.Accuracy
+	e.Improvement = v.Improvement
+	e.Fixed = v.Fixed.Rating
+	return nil
+}
@@ -0,0 +1,90 @@
+package rating_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mashiike/rating"
+)
+
+func TestStructuredRatingJSON(t *testing.T) {
+	r0 := rating.NewGlicko2(0.123456789, 0.987654321, 0.0599999)
+	data, err := json.Marshal(rating.StructuredRating{Rating: r0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"strength":`, `"deviation":`, `"volatility":`, `"mu":`, `"phi":`, `"sigma":`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("%s does not contain %s", data, key)
+		}
+	}
+	var r1 rating.Rating
+	if err := json.Unmarshal(data, &r1); err != nil {
+		t.Fatal(err)
+	}
+	if r1 != r0 {
+		t.Errorf("r1 = %#v, want %#v", r1, r0)
+	}
+
+	var r2 rating.StructuredRating
+	if err := json.Unmarshal([]byte(`{"strength":1700,"deviation":100,"volatility":0.06}`), &r2); err != nil {
+		t.Fatal(err)
+	}
+	if got := r2.Format(rating.DefaultFormat); got != "1700.0p-200.0v=0.06" {
+		t.Errorf("r2 = %s", got)
+	}
+	if err := json.Unmarshal([]byte(`"1500.0p-700.0v=0.25"`), &r2); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"strength":1700}`), &r2); err == nil {
+		t.Error("missing fields are accepted")
+	}
+}
+
+func TestSetJSONForm(t *testing.T) {
+	defer rating.SetJSONForm(rating.JSONString)
+	r := rating.Default(0.06)
+	rating.SetJSONForm(rating.JSONStructured)
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"strength":1500,"deviation":350,"volatility":0.06,"mu":0,"phi":2.014761872416068,"sigma":0.06}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+	rating.SetJSONForm(rating.JSONString)
+	data, err = json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"1500.0p-700.0v=0.06"`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestEstimatedJSON(t *testing.T) {
+	e0 := rating.NewEstimated(rating.New(1623.37, 91.1, 0.0612))
+	if err := e0.ApplyMatch(rating.Default(0.06), rating.ScoreWin); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(e0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e1 := rating.NewEstimated(rating.Rating{})
+	if err := json.Unmarshal(data, e1); err != nil {
+		t.Fatal(err)
+	}
+	if e1.Fixed != e0.Fixed || e1.Accuracy != e0.Accuracy || e1.Improvement != e0.Improvement {
+		t.Errorf("e1 = %s, want %s", data, data)
+	}
+
+	legacy := `{"accuracy":0.5,"improvement":0.25,"fixed":"1500.0p-700.0v=0.06"}`
+	if err := json.Unmarshal([]byte(legacy), e1); err != nil {
+		t.Fatal(err)
+	}
+	if e1.Accuracy != 0.5 || e1.Improvement != 0.25 || e1.Fixed.Format(rating.DefaultFormat) != "1500.0p-700.0v=0.06" {
+		t.Errorf("e1 = %v", e1)
+	}
+}
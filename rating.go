@@ -44,6 +44,44 @@ const (
 	ScoreDraw = float64(0.5)
 )
 
+// Precision is the number of decimal places of display values.
+// A negative number means no truncation.
+// The getters of Rating are not truncated, the truncation for display is the explicit step by Precision.
+type Precision struct {
+	Strength    int
+	Deviation   int
+	Volatility  int
+	Probability int
+}
+
+// DisplayPrecision is the conventional precision of display values, such as DisplayPrecision.Values(r).
+// Nothing in this package truncates by it implicitly.
+var DisplayPrecision = Precision{
+	Strength:    2,
+	Deviation:   2,
+	Volatility:  6,
+	Probability: 4,
+}
+
+// Values returns strength, deviation and volatility truncated by this precision.
+func (p Precision) Values(r Rating) (strength, deviation, volatility float64) {
+	return p.truncate(r.Strength(), p.Strength),
+		p.truncate(r.Deviation(), p.Deviation),
+		p.truncate(r.Volatility(), p.Volatility)
+}
+
+// WinProb returns the winning probability of r against o truncated by this precision.
+func (p Precision) WinProb(r, o Rating) float64 {
+	return p.truncate(r.WinProb(o), p.Probability)
+}
+
+func (p Precision) truncate(x float64, digits int) float64 {
+	if digits < 0 {
+		return x
+	}
+	return nthFloor(x, float64(digits))
+}
+
 //Rating is a structure to evaluate the strength of a player / team.
 type Rating struct {
 
@@ -87,15 +125,20 @@ func NewVolatility(start, count float64) float64 {
 	)
 }
 
-//New is a constractor for Rating
-func New(strength, deviation, volatility float64) Rating {
+//NewGlicko2 is a constractor for Rating from the values on the Glicko-2 scale.
+func NewGlicko2(mu, phi, sigma float64) Rating {
 	return Rating{
-		mu:    (strength - centerValue) / convartRate,
-		phi:   deviation / convartRate,
-		sigma: volatility,
+		mu:    mu,
+		phi:   phi,
+		sigma: sigma,
 	}
 }
 
+//New is a constractor for Rating
+func New(strength, deviation, volatility float64) Rating {
+	return DefaultScale.New(strength, deviation, volatility)
+}
+
 //Average returns the average strength of multiple Ratings
 func Average(ratings []Rating) Rating {
 	totalMu := 0.0
@@ -117,39 +160,67 @@ func Average(ratings []Rating) Rating {
 
 // Default is return default rating for starting Player/Team.
 func Default(volatility float64) Rating {
-	return New(InitialStrength, InitialDeviation, volatility)
+	return DefaultScale.Default(volatility)
 }
 
 // Strength is return value of strength, as rating general value.
+// It is not truncated, use Precision to truncate it for display.
 func (r Rating) Strength() float64 {
-	return nthFloor(r.mu*convartRate+centerValue, 2)
+	return DefaultScale.Strength(r)
 }
 
 // Deviation is return RD(Rating Deviation) as general value.
+// It is not truncated, use Precision to truncate it for display.
 func (r Rating) Deviation() float64 {
-	return nthFloor(r.phi*convartRate, 2)
+	return DefaultScale.Deviation(r)
 }
 
 // Volatility is return Rating volatility.
+// It is not truncated, use Precision to truncate it for display.
 func (r Rating) Volatility() float64 {
-	return nthFloor(r.sigma, 6)
+	return r.sigma
+}
+
+// Mu is return strength on the Glicko-2 scale.
+func (r Rating) Mu() float64 {
+	return r.mu
+}
+
+// Phi is return RD on the Glicko-2 scale.
+func (r Rating) Phi() float64 {
+	return r.phi
+}
+
+// Sigma is return volatility, it is the same on the Glicko-2 scale.
+func (r Rating) Sigma() float64 {
+	return r.sigma
+}
+
+// Shift returns the rating whose strength is moved by diff.
+func (r Rating) Shift(diff float64) Rating {
+	return DefaultScale.Shift(r, diff)
+}
+
+// Widen returns the rating whose RD is spread by deviation, as sqrt(RD^2 + deviation^2).
+// It is same as that RD increases when there is no match during the rating period.
+func (r Rating) Widen(deviation float64) Rating {
+	return DefaultScale.Widen(r, deviation)
+}
+
+// Diff returns the strength difference r - o without truncation.
+func (r Rating) Diff(o Rating) float64 {
+	return DefaultScale.Diff(r, o)
 }
 
 //Interval is return value of strength 95% confidence interval.
 func (r Rating) Interval() (float64, float64) {
-	s := r.Strength()
-	rd2 := r.Deviation() * 2
-	return s - rd2, s + rd2
+	return DefaultScale.Interval(r)
 }
 
-//IsDifferent is a function to check the significance of Rating
+//IsDifferent is a function to check the significance of Rating at the 95% confidence level.
+//Note that Interval is ±2 deviations, slightly wider than 95%. See IsDifferentAt and IntervalAt for other levels.
 func (r Rating) IsDifferent(o Rating) bool {
-	y := r.mu - o.mu
-	z := y / math.Hypot(r.phi, o.phi)
-	if math.Abs(z) > zscore95 {
-		return true
-	}
-	return false
+	return math.Abs(r.zTest(o)) > zscore95
 }
 
 //IsStronger is checker function. this rating r is storonger than rating o.
@@ -170,8 +241,14 @@ func (r Rating) IsWeeker(o Rating) bool {
 
 // WinProb is estimate winning probability,
 // this value 1500 and 1700, both RD is 0 => P(1700 is win) = 0.76
+// It is not truncated, use Precision to truncate it for display.
 func (r Rating) WinProb(o Rating) float64 {
-	return nthFloor(fE(r.mu, o.mu, math.Hypot(r.phi, o.phi)), 4)
+	return fE(r.mu, o.mu, math.Hypot(r.phi, o.phi))
+}
+
+// WinProbWithAdvantage is estimate winning probability with the advantage over the opponent on the strength scale.
+func (r Rating) WinProbWithAdvantage(o Rating, advantage float64) float64 {
+	return r.Shift(advantage).WinProb(o)
 }
 
 func float64ToByte(float float64) []byte {
@@ -188,7 +265,24 @@ func byteToFloat64(bytes []byte) float64 {
 	return math.Float64frombits(bits)
 }
 
-const ratingBinaryVersion byte = 1
+func float32ToByte(float float64) []byte {
+	bits := math.Float32bits(float32(float))
+	bytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(bytes, bits)
+
+	return bytes
+}
+
+func byteToFloat32(bytes []byte) float64 {
+	bits := binary.LittleEndian.Uint32(bytes)
+
+	return float64(math.Float32frombits(bits))
+}
+
+const (
+	ratingBinaryVersion        byte = 1
+	ratingBinaryVersionCompact byte = 2
+)
 
 // MarshalBinary implements the encoding.BinaryMarshaler interface.
 func (r Rating) MarshalBinary() ([]byte, error) {
@@ -200,30 +294,52 @@ func (r Rating) MarshalBinary() ([]byte, error) {
 	return b, nil
 }
 
+// MarshalBinaryCompact is the version 2 binary form for memory-constrained caches.
+// The values are stored as float32, 13 bytes. UnmarshalBinary can read it.
+func (r Rating) MarshalBinaryCompact() ([]byte, error) {
+	b := make([]byte, 0, 13)
+	b = append(b, ratingBinaryVersionCompact)
+	b = append(b, float32ToByte(r.mu)...)
+	b = append(b, float32ToByte(r.phi)...)
+	b = append(b, float32ToByte(r.sigma)...)
+	return b, nil
+}
+
 // UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+// Both version 1 and the compact version 2 are accepted.
 func (r *Rating) UnmarshalBinary(data []byte) error {
 	buf := data
 	if len(buf) == 0 {
 		return errors.New("Rating.UnmarshalBinary: no data")
 	}
 
-	if buf[0] != ratingBinaryVersion {
+	switch buf[0] {
+	case ratingBinaryVersion:
+		if len(buf) != 25 {
+			return errors.New("Rating.UnmarshalBinary: invalid length")
+		}
+		r.mu = byteToFloat64(buf[1:9])
+		r.phi = byteToFloat64(buf[9:17])
+		r.sigma = byteToFloat64(buf[17:25])
+	case ratingBinaryVersionCompact:
+		if len(buf) != 13 {
+			return errors.New("Rating.UnmarshalBinary: invalid length")
+		}
+		r.mu = byteToFloat32(buf[1:5])
+		r.phi = byteToFloat32(buf[5:9])
+		r.sigma = byteToFloat32(buf[9:13])
+	default:
 		return errors.New("Rating.UnmarshalBinary: unsupported version")
 	}
-
-	if len(buf) != 25 {
-		return errors.New("Rating.UnmarshalBinary: invalid length")
-	}
-
-	r.mu = byteToFloat64(buf[1:9])
-	r.phi = byteToFloat64(buf[9:17])
-	r.sigma = byteToFloat64(buf[17:25])
 	return nil
 }
 
 // MarshalJSON implements the json.Marshaler interface.
-// The rating is a quoted string in Default format
+// The rating is a quoted string in Default format, or a structured object after SetJSONForm(JSONStructured).
 func (r Rating) MarshalJSON() ([]byte, error) {
+	if JSONFormOf() == JSONStructured {
+		return StructuredRating{r}.MarshalJSON()
+	}
 	b := make([]byte, 0, len(DefaultFormat)+2)
 	b = append(b, '"')
 	b = r.AppendFormat(b, DefaultFormat)
@@ -232,11 +348,14 @@ func (r Rating) MarshalJSON() ([]byte, error) {
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface.
-// The rating is expected to be a quoted string in Default format.
+// The rating is expected to be a quoted string in Default format or a structured object, regardless of the JSONForm.
 func (r *Rating) UnmarshalJSON(data []byte) error {
 	if string(data) == "null" {
 		return nil
 	}
+	if len(data) > 0 && data[0] == '{' {
+		return r.unmarshalStructured(data)
+	}
 	var err error
 	*r, err = Parse(`"`+DefaultFormat+`"`, string(data))
 	return err
@@ -295,8 +414,36 @@ func NewEstimated(rating Rating) *Estimated {
 	}
 }
 
+// Clone returns a copy of the estimated state.
+func (e *Estimated) Clone() *Estimated {
+	e.Lock()
+	defer e.Unlock()
+	return &Estimated{
+		Accuracy:    e.Accuracy,
+		Improvement: e.Improvement,
+		Fixed:       e.Fixed,
+	}
+}
+
+// Restore overwrites the estimated state with the given one, such as a Clone taken before.
+func (e *Estimated) Restore(from *Estimated) {
+	from = from.Clone()
+	e.Lock()
+	defer e.Unlock()
+	e.Accuracy = from.Accuracy
+	e.Improvement = from.Improvement
+	e.Fixed = from.Fixed
+}
+
 // ApplyMatch reflects match results in the training estimates.
 func (e *Estimated) ApplyMatch(opponent Rating, score float64) error {
+	return e.ApplyMatchWithAdvantage(opponent, score, 0.0)
+}
+
+// ApplyMatchWithAdvantage is like ApplyMatch, with the advantage over the opponent on the strength scale,
+// such as home advantage, handicap or first-move advantage. If it is the opponent's advantage, it is negative.
+// The expected score is calculated as if the strength were higher by advantage.
+func (e *Estimated) ApplyMatchWithAdvantage(opponent Rating, score, advantage float64) error {
 	e.Lock()
 	defer e.Unlock()
 	if score < 0.0 || score > 1.0 {
@@ -304,7 +451,7 @@ func (e *Estimated) ApplyMatch(opponent Rating, score float64) error {
 	}
 	tmp := e.Improvement * e.Accuracy
 	valg := fg(opponent.phi)
-	valE := fE(e.Fixed.mu, opponent.mu, opponent.phi)
+	valE := fE(e.Fixed.mu+DefaultScale.Phi(advantage), opponent.mu, opponent.phi)
 	e.Accuracy += valg * valg * valE * (1.0 - valE)
 	tmp += valg * (score - valE)
 	e.Improvement = tmp / e.Accuracy
@@ -313,17 +460,19 @@ func (e *Estimated) ApplyMatch(opponent Rating, score float64) error {
 
 // Rating returns the current estimate
 func (e *Estimated) Rating() Rating {
-	return e.computeRating(e.Fixed.sigma)
+	e.Lock()
+	defer e.Unlock()
+	return computeRating(e.Fixed, e.Accuracy, e.Improvement, e.Fixed.sigma)
 }
 
-func (e *Estimated) computeRating(sigmaDash float64) Rating {
-	phiAsta := math.Hypot(e.Fixed.phi, sigmaDash)
-	phiDash := 1.0 / math.Sqrt(1.0/(math.Pow(phiAsta, 2))+e.Accuracy)
+func computeRating(fixed Rating, accuracy, improvement, sigmaDash float64) Rating {
+	phiAsta := math.Hypot(fixed.phi, sigmaDash)
+	phiDash := 1.0 / math.Sqrt(1.0/(math.Pow(phiAsta, 2))+accuracy)
 	if phiDash > startPhi {
 		phiDash = startPhi
 	}
 	return Rating{
-		mu:    e.Fixed.mu + math.Pow(phiDash, 2)*e.Improvement*e.Accuracy,
+		mu:    fixed.mu + math.Pow(phiDash, 2)*improvement*accuracy,
 		phi:   phiDash,
 		sigma: sigmaDash,
 	}
@@ -334,88 +483,107 @@ func (e *Estimated) computeRating(sigmaDash float64) Rating {
 // in ref[1] p.1:
 // "Reasonable choices are between 0.3 and 1.2,
 // though the system should be tested to decide which value results in greatest predictive accuracy. "
+// If the volatility iteration does not converge, Fix returns *ConvergenceError and the rating is not changed.
+// Accuracy and Improvement are reset, so the matches of the period are not counted again in the next period.
 func (e *Estimated) Fix(tau float64) error {
+	_, err := e.FixWithOptions(tau, FixOptions{})
+	return err
+}
+
+// FixWithOptions is like Fix, with the options of the volatility iteration.
+// It returns the diagnostics of the iteration.
+func (e *Estimated) FixWithOptions(tau float64, opts FixOptions) (Diagnostics, error) {
 	e.Lock()
 	defer e.Unlock()
 	if tau <= 0 {
-		return errors.New("tau must be a nonzero positive number")
+		return Diagnostics{}, errTau
+	}
+	fixed, diag, err := fix(e.Fixed, e.Accuracy, e.Improvement, tau, opts)
+	if err != nil {
+		return diag, err
 	}
-	if e.Accuracy == 0.0 {
+	// the next rating period starts from the new rating without matches
+	e.Fixed = fixed
+	e.Accuracy = 0.0
+	e.Improvement = 0.0
+	return diag, nil
+}
+
+var errTau = errors.New("tau must be a nonzero positive number")
+
+// fix determines the new rating from the estimates of the rating period.
+func fix(fixed Rating, accuracy, improvement, tau float64, opts FixOptions) (Rating, Diagnostics, error) {
+	if accuracy == 0.0 {
 		// if estimated accuracy is zero, can not apply. because maybe no matches.
 		// In this case, rating value and volatility parameters remain the same, but the rating deviation increases
-		e.Fixed.phi = math.Hypot(e.Fixed.phi, e.Fixed.sigma)
-		if e.Fixed.phi > startPhi {
-			e.Fixed.phi = startPhi
+		next := fixed
+		next.phi = math.Hypot(fixed.phi, fixed.sigma)
+		if next.phi > startPhi {
+			next.phi = startPhi
 		}
-		return nil
+		return opts.guard(fixed, next), Diagnostics{Solver: opts.Solver, Converged: true}, nil
 	}
-	alg := &illinois{
-		tau: tau,
+	f := newVolatilityFunc(fixed, accuracy, improvement, tau)
+	sigmaDash, diag := opts.solve(&f)
+	if !diag.Converged {
+		return fixed, diag, &ConvergenceError{Diagnostics: diag}
 	}
-	e.Fixed = e.computeRating(alg.Do(e))
-	return nil
+	return opts.guard(fixed, computeRating(fixed, accuracy, improvement, sigmaDash)), diag, nil
 }
 
-type illinois struct {
-	tau     float64
-	a       float64
-	v       float64
-	sqDelta float64
-	sqPhi   float64
+// Preview is the projected ratings for each outcome of a match.
+type Preview struct {
+	// Current is the rating before the match
+	Current Rating
+	Win     Rating
+	Draw    Rating
+	Lose    Rating
 }
 
-func (alg *illinois) Do(e *Estimated) float64 {
-	alg.a = math.Log(math.Pow(e.Fixed.sigma, 2))
-	A := alg.a
-	B := 0.0
+// Deltas returns the strength differences from Current for each outcome.
+func (p Preview) Deltas() (win, draw, lose float64) {
+	s := p.Current.Strength()
+	return p.Win.Strength() - s, p.Draw.Strength() - s, p.Lose.Strength() - s
+}
 
-	alg.sqDelta = math.Pow(e.Improvement, 2)
-	alg.sqPhi = math.Pow(e.Fixed.phi, 2)
-	alg.v = 1.0 / e.Accuracy
+// Preview projects the rating after one more match against the opponent and the end of the rating period.
+// The estimated state is not changed.
+func (e *Estimated) Preview(opponent Rating, tau float64) (Preview, error) {
+	return e.PreviewMatches([]Rating{opponent}, tau)
+}
 
-	switch {
-	case alg.sqDelta > alg.sqPhi+alg.v:
-		B = math.Log(alg.sqDelta - alg.sqPhi - alg.v)
-	default:
-		valf := 0.0
-		for k := 1; k < iterationLimit+1; k++ {
-			B = (alg.a - float64(k)*alg.tau)
-			valf = alg.fx(B)
-			if valf >= 0.0 {
-				break
-			}
-		}
+// PreviewMatches is like Preview, but all opponents are played with the same outcome.
+// For example, in a multiplayer match, Win means to beat all the opponents.
+func (e *Estimated) PreviewMatches(opponents []Rating, tau float64) (Preview, error) {
+	p := Preview{
+		Current: e.Rating(),
 	}
-
-	valfA := alg.fx(A)
-	valfB := alg.fx(B)
-
-	for i := 0; i < iterationLimit; i++ {
-		if math.Abs(B-A) <= epsiron {
-			break
+	outcomes := []struct {
+		score float64
+		dest  *Rating
+	}{
+		{ScoreWin, &p.Win},
+		{ScoreDraw, &p.Draw},
+		{ScoreLose, &p.Lose},
+	}
+	for _, outcome := range outcomes {
+		clone := e.Clone()
+		for _, opponent := range opponents {
+			if err := clone.ApplyMatch(opponent, outcome.score); err != nil {
+				return p, err
+			}
 		}
-		C := A + ((A-B)*valfA)/(valfB-valfA)
-		valfC := alg.fx(C)
-		switch {
-		case valfB*valfC < 0.0:
-			A = B
-			valfA = valfB
-		default:
-			valfA /= 2.0
+		if err := clone.Fix(tau); err != nil {
+			return p, err
 		}
-		B = C
-		valfB = valfC
+		*outcome.dest = clone.Fixed
 	}
-
-	return math.Exp(A / 2.0)
+	return p, nil
 }
 
-func (alg *illinois) fx(x float64) float64 {
-	sumVal := alg.sqDelta + alg.sqPhi + alg.v
-	diffVal := alg.sqDelta - alg.sqPhi - alg.v
-	firstTerm := (math.Exp(x) * (diffVal - math.Exp(x))) / (2 * sumVal * sumVal)
-	secondTerm := (x - alg.a) / (math.Pow(alg.tau, 2))
-	return firstTerm - secondTerm
+//PreviewUpdate projects the rating after a match against the opponent, without any side effects.
+func (r Rating) PreviewUpdate(opponent Rating, tau float64) (Preview, error) {
+	return NewEstimated(r).Preview(opponent, tau)
 }
 
 // Truncate at n decimal places
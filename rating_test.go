@@ -3,6 +3,7 @@ package rating_test
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/mashiike/rating"
@@ -83,7 +84,7 @@ func TestRatingCompare(t *testing.T) {
 				t.Errorf("unexpected IsStronger result: %v", got)
 			}
 
-			gotWin := left.WinProb(right)
+			gotWin := rating.DisplayPrecision.WinProb(left, right)
 			if gotWin != c.winProb {
 				t.Errorf("unexpected WinProb result: %v", gotWin)
 			}
@@ -158,15 +159,101 @@ func TestAverage(t *testing.T) {
 	}
 	got := rating.Average(ratings)
 	t.Log(got.Deviation())
-	expected := rating.New(1600.0, 86.60, 0.22)
-	if got.Strength() != expected.Strength() {
-		t.Errorf("rating.Average Strength got %v, expected %v", got.Strength(), expected.Strength())
+	gotStrength, gotDeviation, gotVolatility := rating.DisplayPrecision.Values(got)
+	expectedStrength, expectedDeviation, expectedVolatility := rating.DisplayPrecision.Values(rating.New(1600.0, 86.60, 0.22))
+	if gotStrength != expectedStrength {
+		t.Errorf("rating.Average Strength got %v, expected %v", gotStrength, expectedStrength)
 	}
-	if got.Deviation() != expected.Deviation() {
-		t.Errorf("rating.Average Deviation got %v, expected %v", got.Deviation(), expected.Deviation())
+	if gotDeviation != expectedDeviation {
+		t.Errorf("rating.Average Deviation got %v, expected %v", gotDeviation, expectedDeviation)
 	}
-	if got.Volatility() != expected.Volatility() {
-		t.Errorf("rating.Average Volatility got %v, expected %v", got.Volatility(), expected.Volatility())
+	if gotVolatility != expectedVolatility {
+		t.Errorf("rating.Average Volatility got %v, expected %v", gotVolatility, expectedVolatility)
 	}
 
 }
+
+func TestPreviewUpdate(t *testing.T) {
+	player := rating.New(1500.0, 200.0, 0.06)
+	opponent := rating.New(1400.0, 30.0, 0.06)
+	preview, err := player.PreviewUpdate(opponent, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name  string
+		score float64
+		got   rating.Rating
+	}{
+		{"win", rating.ScoreWin, preview.Win},
+		{"draw", rating.ScoreDraw, preview.Draw},
+		{"lose", rating.ScoreLose, preview.Lose},
+	}
+	for _, c := range cases {
+		expected, err := player.Update([]rating.Rating{opponent}, []float64{c.score}, 0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.got != expected {
+			t.Errorf("%s preview got %v, expected %v", c.name, c.got, expected)
+		}
+	}
+	win, _, lose := preview.Deltas()
+	if win <= 0.0 || lose >= 0.0 {
+		t.Errorf("unexpected deltas: win=%v lose=%v", win, lose)
+	}
+}
+
+func TestAccessorsNotTruncated(t *testing.T) {
+	r := rating.New(1523.456789, 87.654321, 0.0612345678)
+	if got := r.Strength(); math.Abs(got-1523.456789) > 1e-9 {
+		t.Errorf("Strength got %v", got)
+	}
+	if got := r.Deviation(); math.Abs(got-87.654321) > 1e-9 {
+		t.Errorf("Deviation got %v", got)
+	}
+	if got := r.Volatility(); got != 0.0612345678 {
+		t.Errorf("Volatility got %v", got)
+	}
+	if got := rating.NewGlicko2(r.Mu(), r.Phi(), r.Sigma()); got != r {
+		t.Errorf("NewGlicko2 got %v, expected %v", got, r)
+	}
+	if got := r.Shift(100.0).Diff(r); math.Abs(got-100.0) > 1e-9 {
+		t.Errorf("Shift/Diff got %v", got)
+	}
+	if got := r.Widen(50.0).Deviation(); math.Abs(got-math.Hypot(87.654321, 50.0)) > 1e-9 {
+		t.Errorf("Widen got %v", got)
+	}
+	o := rating.Default(0.06)
+	if got := (rating.Precision{Probability: -1}).WinProb(r, o); got != r.WinProb(o) {
+		t.Errorf("Precision.WinProb got %v", got)
+	}
+	s, d, v := rating.Precision{Strength: 0, Deviation: 1, Volatility: 3}.Values(r)
+	if s != 1523.0 || d != 87.6 || v != 0.061 {
+		t.Errorf("Precision.Values got %v %v %v", s, d, v)
+	}
+}
+
+func TestFixStartsNewPeriod(t *testing.T) {
+	e := rating.NewEstimated(rating.New(1500.0, 200.0, 0.06))
+	if err := e.ApplyMatch(rating.New(1400.0, 30.0, 0.06), rating.ScoreWin); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Fix(0.5); err != nil {
+		t.Fatal(err)
+	}
+	if e.Accuracy != 0.0 || e.Improvement != 0.0 {
+		t.Errorf("estimates are not reset: accuracy %v, improvement %v", e.Accuracy, e.Improvement)
+	}
+	fixed := e.Fixed
+	//the next period without matches only widens the deviation, the win is not counted again
+	if err := e.Fix(0.5); err != nil {
+		t.Fatal(err)
+	}
+	if e.Fixed.Mu() != fixed.Mu() || e.Fixed.Phi() <= fixed.Phi() {
+		t.Errorf("the matches of the previous period are counted again: %v -> %v", fixed, e.Fixed)
+	}
+	if e.Rating() != rating.NewEstimated(e.Fixed).Rating() {
+		t.Errorf("unexpected rating after the empty period: %v", e.Rating())
+	}
+}
@@ -0,0 +1,379 @@
+package ratingutil
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/mashiike/rating"
+	"github.com/pkg/errors"
+)
+
+const (
+	binaryVersion        byte = 1
+	binaryVersionCompact byte = 2
+)
+
+//MarshalBinary implements the encoding.BinaryMarshaler interface.
+//It contains the name, the id, the estimated state, the start of the rating period, the games, the floor and all contexts of the player.
+//A player in a context is encoded with the player of the default context.
+func (p *Player) MarshalBinary() ([]byte, error) {
+	return p.marshalBinary(binaryVersion)
+}
+
+//MarshalBinaryCompact is the version 2 binary form for memory-constrained caches.
+//The ratings are stored as float32, UnmarshalBinary can read it.
+func (p *Player) MarshalBinaryCompact() ([]byte, error) {
+	return p.marshalBinary(binaryVersionCompact)
+}
+
+func (p *Player) marshalBinary(version byte) ([]byte, error) {
+	root := p.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	root.contextsMu.Lock()
+	defer root.contextsMu.Unlock()
+
+	e := &encoder{version: version}
+	e.byte(version)
+	e.string(root.name)
+	e.string(root.id)
+	if err := e.period(root); err != nil {
+		return nil, err
+	}
+	e.games(root)
+	e.bool(root.hasFloor)
+	e.float(root.floor)
+	keys := make([]string, 0, len(root.contexts))
+	for key := range root.contexts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	e.uvarint(uint64(len(keys)))
+	for _, key := range keys {
+		e.string(key)
+		if err := e.period(root.contexts[key]); err != nil {
+			return nil, errors.Wrapf(err, "context %s", key)
+		}
+		e.games(root.contexts[key])
+	}
+	return e.buf, nil
+}
+
+//UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+//Both version 1 and the compact version 2 are accepted.
+func (p *Player) UnmarshalBinary(data []byte) error {
+	d := &decoder{buf: data}
+	version := d.byte()
+	if d.err == nil && version != binaryVersion && version != binaryVersionCompact {
+		return errors.New("Player.UnmarshalBinary: unsupported version")
+	}
+	d.version = version
+	decoded := &Player{name: d.string(), id: d.string()}
+	d.period(decoded)
+	d.games(decoded)
+	decoded.hasFloor = d.bool()
+	decoded.floor = d.float()
+	n := d.uvarint()
+	if d.err == nil && n > uint64(len(d.buf)) {
+		d.err = errors.New("invalid number of contexts")
+	}
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		key := d.string()
+		if d.err == nil && key == "" {
+			d.err = errors.New("empty context key")
+		}
+		child := &Player{
+			name:    decoded.name,
+			context: key,
+			parent:  p,
+		}
+		d.period(child)
+		d.games(child)
+		if decoded.contexts == nil {
+			decoded.contexts = make(map[string]*Player)
+		}
+		decoded.contexts[key] = child
+	}
+	if d.err == nil && len(d.buf) != 0 {
+		d.err = errors.New("trailing data")
+	}
+	if d.err != nil {
+		return errors.Wrap(d.err, "Player.UnmarshalBinary")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.contextsMu.Lock()
+	defer p.contextsMu.Unlock()
+	if p.seq == 0 {
+		p.seq = nextPlayerSeq()
+	}
+	p.id = decoded.id
+	p.restore(decoded)
+	p.context = ""
+	p.parent = nil
+	//the contexts are restored in place, so that the players of the contexts held by the callers are kept up to date
+	for key, child := range decoded.contexts {
+		child.seq = p.seq
+		current, ok := p.contexts[key]
+		if !ok {
+			current, ok = p.removed[key]
+			delete(p.removed, key)
+		}
+		if ok && current.parent == p {
+			current.restore(child)
+			decoded.contexts[key] = current
+		}
+	}
+	for key, current := range p.contexts {
+		if _, ok := decoded.contexts[key]; !ok && current.parent == p {
+			if p.removed == nil {
+				p.removed = make(map[string]*Player)
+			}
+			p.removed[key] = current
+		}
+	}
+	p.contexts = decoded.contexts
+	return nil
+}
+
+//restore copies the state of the decoded player, it must be called with the player locked or not registered
+func (p *Player) restore(decoded *Player) {
+	p.name = decoded.name
+	p.estimated = decoded.estimated
+	p.fixedAt = decoded.fixedAt
+	p.floor = decoded.floor
+	p.hasFloor = decoded.hasFloor
+	p.games = decoded.games
+	p.graduated = decoded.graduated
+}
+
+//MarshalBinary implements the encoding.BinaryMarshaler interface.
+//It contains the name and the binary form of all members.
+func (t *Team) MarshalBinary() ([]byte, error) {
+	return t.marshalBinary(binaryVersion, (*Player).MarshalBinary)
+}
+
+//MarshalBinaryCompact is the version 2 binary form for memory-constrained caches.
+//The members are encoded by Player.MarshalBinaryCompact, UnmarshalBinary can read it.
+func (t *Team) MarshalBinaryCompact() ([]byte, error) {
+	return t.marshalBinary(binaryVersionCompact, (*Player).MarshalBinaryCompact)
+}
+
+func (t *Team) marshalBinary(version byte, marshal func(*Player) ([]byte, error)) ([]byte, error) {
+	e := &encoder{version: version}
+	e.byte(version)
+	e.string(t.name)
+	e.uvarint(uint64(len(t.members)))
+	for _, member := range t.members {
+		b, err := marshal(member)
+		if err != nil {
+			return nil, errors.Wrapf(err, "member %s", member.Name())
+		}
+		e.bytes(b)
+	}
+	return e.buf, nil
+}
+
+//UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+//Both version 1 and the compact version 2 are accepted. The members are decoded as new players.
+func (t *Team) UnmarshalBinary(data []byte) error {
+	d := &decoder{buf: data}
+	version := d.byte()
+	if d.err == nil && version != binaryVersion && version != binaryVersionCompact {
+		return errors.New("Team.UnmarshalBinary: unsupported version")
+	}
+	name := d.string()
+	n := d.uvarint()
+	if d.err == nil && n > uint64(len(d.buf)) {
+		d.err = errors.New("invalid number of members")
+	}
+	members := make(Players, 0, n)
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		b := d.bytes()
+		if d.err != nil {
+			break
+		}
+		member := &Player{}
+		if err := member.UnmarshalBinary(b); err != nil {
+			d.err = errors.Wrapf(err, "member %d", i)
+			break
+		}
+		members = append(members, member)
+	}
+	if d.err == nil && len(d.buf) != 0 {
+		d.err = errors.New("trailing data")
+	}
+	if d.err != nil {
+		return errors.Wrap(d.err, "Team.UnmarshalBinary")
+	}
+	t.name = name
+	t.members = members
+	return nil
+}
+
+//encoder appends the fields of the binary form
+type encoder struct {
+	version byte
+	buf     []byte
+}
+
+func (e *encoder) byte(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.byte(1)
+		return
+	}
+	e.byte(0)
+}
+
+func (e *encoder) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	e.buf = append(e.buf, b[:n]...)
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) string(s string) {
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) float(v float64) {
+	if e.version == binaryVersionCompact {
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(v)))
+		e.buf = append(e.buf, b[:]...)
+		return
+	}
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	e.buf = append(e.buf, b[:]...)
+}
+
+//period encodes the start of the rating period and the estimated state of the player
+func (e *encoder) period(p *Player) error {
+	at, err := p.fixedAt.MarshalBinary()
+	if err != nil {
+		return errors.Wrap(err, "fixedAt")
+	}
+	e.bytes(at)
+	var estimated []byte
+	if e.version == binaryVersionCompact {
+		estimated, err = p.estimated.MarshalBinaryCompact()
+	} else {
+		estimated, err = p.estimated.MarshalBinary()
+	}
+	if err != nil {
+		return errors.Wrap(err, "estimated")
+	}
+	e.bytes(estimated)
+	return nil
+}
+
+func (e *encoder) games(p *Player) {
+	e.uvarint(uint64(p.games))
+	e.bool(p.graduated)
+}
+
+//decoder reads the fields of the binary form, the first error is kept in err and the rest of reads are ignored
+type decoder struct {
+	version byte
+	buf     []byte
+	err     error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errors.New("unexpected end of data")
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) byte() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) bool() bool {
+	return d.byte() != 0
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = errors.New("invalid varint")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uvarint()
+	if d.err == nil && n > uint64(len(d.buf)) {
+		d.err = errors.New("unexpected end of data")
+		return nil
+	}
+	return d.next(int(n))
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) float() float64 {
+	if d.version == binaryVersionCompact {
+		if b := d.next(4); b != nil {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		}
+		return 0
+	}
+	if b := d.next(8); b != nil {
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *decoder) period(p *Player) {
+	at := d.bytes()
+	estimated := d.bytes()
+	if d.err != nil {
+		return
+	}
+	if err := p.fixedAt.UnmarshalBinary(at); err != nil {
+		d.err = errors.Wrap(err, "fixedAt")
+		return
+	}
+	p.estimated = rating.NewEstimated(rating.Rating{})
+	if err := p.estimated.UnmarshalBinary(estimated); err != nil {
+		d.err = errors.Wrap(err, "estimated")
+	}
+}
+
+func (d *decoder) games(p *Player) {
+	games := d.uvarint()
+	if d.err == nil && games > math.MaxInt32 {
+		d.err = errors.New("invalid number of games")
+	}
+	p.games = int(games)
+	p.graduated = d.bool()
+}
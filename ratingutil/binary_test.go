@@ -0,0 +1,120 @@
+package ratingutil_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/mashiike/rating/ratingutil"
+)
+
+func TestPlayerMarshalBinary(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	svc := ratingutil.New(ratingutil.NewConfig().WithClock(clock))
+	p0 := svc.NewDefaultPlayer("alice")
+	opponent := svc.NewDefaultPlayer("bob")
+	p0.SetFloor(1200.0)
+	match, err := svc.NewMatchInContext("ranked", p0, opponent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := match.Add(p0, 1.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Apply(match); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, compact := range []bool{false, true} {
+		marshal := p0.MarshalBinary
+		if compact {
+			marshal = p0.MarshalBinaryCompact
+		}
+		enc, err := marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p1 := &ratingutil.Player{}
+		if err := p1.UnmarshalBinary(enc); err != nil {
+			t.Fatal(err)
+		}
+		if p1.Name() != "alice" {
+			t.Errorf("compact=%v: name = %q", compact, p1.Name())
+		}
+		if p1.Games() != 0 || p1.Context("ranked", svc.Config).Games() != 1 {
+			t.Errorf("compact=%v: games = %d, %d", compact, p1.Games(), p1.Context("ranked", svc.Config).Games())
+		}
+		for n := 1; n <= 3; n++ {
+			if err := (&ratingutil.Player{}).UnmarshalBinary(enc[:len(enc)-n]); err == nil {
+				t.Errorf("compact=%v: data truncated by %d is accepted", compact, n)
+			}
+		}
+		if keys := p1.Contexts(); len(keys) != 1 || keys[0] != "ranked" {
+			t.Errorf("compact=%v: contexts = %v", compact, keys)
+		}
+		want := p0.Ratings()
+		for key, r := range p1.Ratings() {
+			if got := r.Format(rating.PlusMinusFormat); got != want[key].Format(rating.PlusMinusFormat) {
+				t.Errorf("compact=%v: context %q = %s, want %s", compact, key, got, want[key].Format(rating.PlusMinusFormat))
+			}
+		}
+		if !compact {
+			enc2, _ := p1.MarshalBinary()
+			if string(enc) != string(enc2) {
+				t.Errorf("round trip %x != %x", enc, enc2)
+			}
+		}
+	}
+}
+
+func TestTeamMarshalBinary(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig())
+	t0 := svc.NewTeam("team", ratingutil.Players{
+		svc.NewPlayerOnScale("alice", 1700.0, 80.0, svc.Config.Now()),
+		svc.NewPlayerOnScale("bob", 1400.0, 120.0, svc.Config.Now()),
+	})
+	enc, err := t0.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t1 := &ratingutil.Team{}
+	if err := t1.UnmarshalBinary(enc); err != nil {
+		t.Fatal(err)
+	}
+	if t1.Name() != "team" || len(t1.Members()) != 2 {
+		t.Fatalf("t1 = %v", t1)
+	}
+	if t1.String() != t0.String() {
+		t.Errorf("t1 = %v, want %v", t1, t0)
+	}
+	if err := t1.UnmarshalBinary(enc[:len(enc)-1]); err == nil {
+		t.Error("truncated data is accepted")
+	}
+}
+
+func FuzzPlayerBinary(f *testing.F) {
+	svc := ratingutil.New(ratingutil.NewConfig())
+	p := svc.NewDefaultPlayer("alice")
+	seed, _ := p.MarshalBinary()
+	f.Add(seed)
+	seed, _ = p.MarshalBinaryCompact()
+	f.Add(seed)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p0 := &ratingutil.Player{}
+		if err := p0.UnmarshalBinary(data); err != nil {
+			return
+		}
+		enc, err := p0.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p1 := &ratingutil.Player{}
+		if err := p1.UnmarshalBinary(enc); err != nil {
+			t.Fatal(err)
+		}
+		enc2, _ := p1.MarshalBinary()
+		if string(enc) != string(enc2) {
+			t.Errorf("round trip %x != %x", enc, enc2)
+		}
+	})
+}
@@ -45,6 +45,52 @@ type Config struct {
 
 	//Fighting prosperity strategy uses round robin by default
 	DefaultApplyStrategy ApplyStrategy
+
+	//Scale is the presentation of ratings, used by NewDefaultPlayer, Service.Format and Service.Parse.
+	Scale rating.Scale
+
+	//Safeguards at the end of the rating period, on the configured Scale. All of them are opt-in.
+	//MinDeviation is a floor of the deviation, so that long-time heavy players can still move. if zero, no floor.
+	MinDeviation float64
+	//MaxChangePerPeriod is the maximum change of strength per rating period. if zero, no limit.
+	MaxChangePerPeriod float64
+	//StrengthFloor is the strength that players can not drop below, enabled when HasStrengthFloor is true.
+	//Player.SetFloor overrides it per player.
+	StrengthFloor    float64
+	HasStrengthFloor bool
+
+	//FixOptions is the options of the volatility iteration. The safeguards fields are set by the above.
+	FixOptions rating.FixOptions
+
+	//ContextWidening is the deviation on the configured Scale added to the seed of a new player context.
+	ContextWidening float64
+
+	//History records the rating time series of each player, if not nil.
+	History HistoryStore
+
+	//Hooks are called when ratings change, if not nil.
+	Hooks *Hooks
+
+	//Instrumentation receives the measurements of the operations, if not nil.
+	Instrumentation Instrumentation
+
+	//Provisional is the provisional phase of new players, if not nil.
+	Provisional *Provisional
+
+	//Prior gives the initial rating of a player from the attributes, used by NewPlayerWithAttributes.
+	Prior PriorProvider
+	//MinPriorDeviationRatio is the minimum deviation of a prior as the ratio to the initial deviation.
+	MinPriorDeviationRatio float64
+
+	//Advantages is the fixed advantage on the configured Scale per match context, such as home advantage.
+	//It is given to the element set by Match.SetAdvantaged, the key of the default context is empty.
+	Advantages map[string]float64
+
+	//LatePolicy decides how to handle a result in an already closed rating period.
+	LatePolicy LatePolicy
+
+	//events buffers the events in a transaction
+	events *eventBuffer
 }
 
 //NewConfig is default configuration
@@ -55,6 +101,10 @@ func NewConfig() *Config {
 		PeriodToResetDeviation: PeriodYear,
 		Tau:                    0.5,
 		DefaultApplyStrategy:   AsRoundrobin,
+		Scale:                  rating.DefaultScale,
+		ContextWidening:        100.0,
+		Hooks:                  NewHooks(),
+		MinPriorDeviationRatio: 0.5,
 	}
 }
 
@@ -89,3 +139,80 @@ func (c *Config) WithApplyStrategy(strategy ApplyStrategy) *Config {
 	}
 	return c
 }
+
+//WithMinDeviation is set MinDeviation to config
+func (c *Config) WithMinDeviation(deviation float64) *Config {
+	c.MinDeviation = deviation
+	return c
+}
+
+//WithMaxChangePerPeriod is set MaxChangePerPeriod to config
+func (c *Config) WithMaxChangePerPeriod(change float64) *Config {
+	c.MaxChangePerPeriod = change
+	return c
+}
+
+//WithStrengthFloor is set StrengthFloor to config
+func (c *Config) WithStrengthFloor(floor float64) *Config {
+	c.StrengthFloor = floor
+	c.HasStrengthFloor = true
+	return c
+}
+
+//fixOptions returns the options of fixing the player rating with the safeguards on the Glicko-2 scale
+func (c *Config) fixOptions(p *Player) rating.FixOptions {
+	opts := c.FixOptions
+	if c.MinDeviation > 0 {
+		opts.MinPhi = c.Scale.Phi(c.MinDeviation)
+	}
+	if c.MaxChangePerPeriod > 0 {
+		opts.MaxChange = c.Scale.Phi(c.MaxChangePerPeriod)
+	}
+	floor, hasFloor := c.StrengthFloor, c.HasStrengthFloor
+	if p.hasFloor {
+		floor, hasFloor = p.floor, true
+	}
+	if hasFloor {
+		opts.Floor = c.Scale.Mu(floor)
+		opts.HasFloor = true
+	}
+	return opts
+}
+
+//WithAdvantage is set the fixed advantage of the match context to config
+func (c *Config) WithAdvantage(context string, advantage float64) *Config {
+	if c.Advantages == nil {
+		c.Advantages = make(map[string]float64)
+	}
+	c.Advantages[context] = advantage
+	return c
+}
+
+//EstimateAdvantage fits the advantage on the configured Scale from the match history, for WithAdvantage.
+func (c *Config) EstimateAdvantage(samples []rating.AdvantageSample) (float64, error) {
+	return c.Scale.EstimateAdvantage(samples)
+}
+
+//WithContextWidening is set ContextWidening to config
+func (c *Config) WithContextWidening(deviation float64) *Config {
+	c.ContextWidening = deviation
+	return c
+}
+
+//WithHistory is set History to config
+func (c *Config) WithHistory(history HistoryStore) *Config {
+	c.History = history
+	return c
+}
+
+//WithScale is set Scale to config
+func (c *Config) WithScale(scale rating.Scale) *Config {
+	c.Scale = scale
+	return c
+}
+
+//WithHooks is set Hooks to config
+func (c *Config) WithHooks(hooks *Hooks) *Config {
+	c.Hooks = hooks
+	return c
+}
@@ -0,0 +1,166 @@
+package ratingutil
+
+import (
+	"sort"
+
+	"github.com/mashiike/rating"
+)
+
+//Context returns the player in the context, such as game mode, map or role.
+//Each context has a separate rating. The empty key is the default context, the player itself.
+//A new context is seeded from a blend of the existing contexts with the deviation widened by Config.ContextWidening.
+func (p *Player) Context(key string, config *Config) *Player {
+	child, _ := p.registerContext(key, config)
+	return child
+}
+
+//registerContext is like Context, and reports whether the context is newly created
+func (p *Player) registerContext(key string, config *Config) (*Player, bool) {
+	root := p.root()
+	if key == "" {
+		return root, false
+	}
+	root.contextsMu.Lock()
+	defer root.contextsMu.Unlock()
+	if child, ok := root.contexts[key]; ok {
+		return child, false
+	}
+	child := root.newContext(key, config)
+	if removed, ok := root.removed[key]; ok {
+		removed.restore(child)
+		delete(root.removed, key)
+		child = removed
+	}
+	if root.contexts == nil {
+		root.contexts = make(map[string]*Player)
+	}
+	root.contexts[key] = child
+	return child, true
+}
+
+//unregisterContexts removes the contexts created by an operation that failed
+func unregisterContexts(created Players) {
+	for _, child := range created {
+		root := child.root()
+		root.contextsMu.Lock()
+		if root.contexts[child.context] == child {
+			delete(root.contexts, child.context)
+			if root.removed == nil {
+				root.removed = make(map[string]*Player)
+			}
+			root.removed[child.context] = child
+		}
+		root.contextsMu.Unlock()
+	}
+}
+
+//Contexts returns the keys of the contexts the player has, except for the default context.
+func (p *Player) Contexts() []string {
+	root := p.root()
+	root.contextsMu.Lock()
+	defer root.contextsMu.Unlock()
+	keys := make([]string, 0, len(root.contexts))
+	for key := range root.contexts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//Ratings returns the current ratings of all contexts, the default context is the empty key.
+func (p *Player) Ratings() map[string]rating.Rating {
+	root := p.root()
+	root.contextsMu.Lock()
+	defer root.contextsMu.Unlock()
+	ratings := make(map[string]rating.Rating, len(root.contexts)+1)
+	ratings[""] = root.Rating()
+	for key, child := range root.contexts {
+		ratings[key] = child.Rating()
+	}
+	return ratings
+}
+
+//ContextKey returns the key of the context of this player, the default context is empty.
+func (p *Player) ContextKey() string {
+	return p.context
+}
+
+func (p *Player) root() *Player {
+	if p.parent != nil {
+		return p.parent
+	}
+	return p
+}
+
+//lookupContext is like Context, but a new context is not registered to the player.
+//It is used to see the rating without changing the player.
+func (p *Player) lookupContext(key string, config *Config) *Player {
+	root := p.root()
+	if key == "" {
+		return root
+	}
+	root.contextsMu.Lock()
+	defer root.contextsMu.Unlock()
+	if child, ok := root.contexts[key]; ok {
+		return child
+	}
+	return root.newContext(key, config)
+}
+
+//newContext must be called with contextsMu locked
+func (p *Player) newContext(key string, config *Config) *Player {
+	ratings := make([]rating.Rating, 0, len(p.contexts)+1)
+	ratings = append(ratings, p.Rating())
+	for _, child := range p.contexts {
+		ratings = append(ratings, child.Rating())
+	}
+	seed := config.widen(rating.Average(ratings), config.ContextWidening)
+	return &Player{
+		seq:       p.seq,
+		name:      p.name,
+		context:   key,
+		parent:    p,
+		estimated: rating.NewEstimated(seed),
+		fixedAt:   p.fixedAt,
+	}
+}
+
+//widen widens the deviation of the rating by the deviation on the configured Scale, up to the initial deviation
+func (c *Config) widen(r rating.Rating, deviation float64) rating.Rating {
+	if deviation > 0 {
+		r = c.Scale.Widen(r, deviation)
+	}
+	if start := c.Scale.Default(r.Sigma()); r.Phi() > start.Phi() {
+		r = rating.NewGlicko2(r.Mu(), start.Phi(), r.Sigma())
+	}
+	return r
+}
+
+//inContext returns the element in the context
+//If created is not nil, a new context is registered to the player and appended to created, otherwise it is not registered.
+func inContext(element Element, key string, config *Config, created *Players) Element {
+	if key == "" {
+		return element
+	}
+	switch e := element.(type) {
+	case *Player:
+		if created == nil {
+			return e.lookupContext(key, config)
+		}
+		child, ok := e.registerContext(key, config)
+		if ok {
+			*created = append(*created, child)
+		}
+		return child
+	case *Team:
+		members := make(Players, 0, len(e.members))
+		for _, member := range e.members {
+			members = append(members, inContext(member, key, config, created).(*Player))
+		}
+		return &Team{
+			name:    e.name,
+			members: members,
+		}
+	}
+	return element
+}
@@ -0,0 +1,128 @@
+package ratingutil_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/mashiike/rating/ratingutil"
+)
+
+func TestPlayerContext(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig().WithHistory(ratingutil.NewMemoryHistory()))
+	sheep := svc.NewPlayer("sheep", rating.New(1700.0, 50.0, svc.Config.InitialVolatility()), svc.Config.Now())
+	goat := svc.NewPlayer("goat", rating.New(1600.0, 50.0, svc.Config.InitialVolatility()), svc.Config.Now())
+
+	match, _ := svc.NewMatchInContext("ranked", sheep, goat)
+	if match.Context() != "ranked" {
+		t.Errorf("unexpected context: %q", match.Context())
+	}
+	seeded := match.Ratings()[sheep]
+	if seeded.Strength() != 1700.0 || seeded.Deviation() <= 100.0 {
+		t.Errorf("unexpected seeded rating: %v", seeded)
+	}
+	if len(sheep.Contexts()) != 0 {
+		t.Error("looking the rating should not create the context")
+	}
+
+	before := sheep.Rating()
+	match.Add(goat, 1.0)
+	if err := svc.Apply(match); err != nil {
+		t.Fatal(err)
+	}
+	if sheep.Rating() != before {
+		t.Errorf("default context is changed: %v", sheep.Rating())
+	}
+	ranked := sheep.Context("ranked", svc.Config)
+	if ranked.ContextKey() != "ranked" || ranked.Name() != "sheep" {
+		t.Errorf("unexpected context player: %v", ranked)
+	}
+	if got := ranked.Rating().Strength(); got >= 1700.0 {
+		t.Errorf("ranked strength should decrease: %v", got)
+	}
+	ratings := sheep.Ratings()
+	if len(ratings) != 2 || ratings["ranked"] != ranked.Rating() {
+		t.Errorf("unexpected ratings: %v", ratings)
+	}
+	if _, err := json.Marshal(ratings); err != nil {
+		t.Error(err)
+	}
+
+	series, err := svc.History("sheep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(series.InContext("ranked")); got != 1 {
+		t.Errorf("unexpected ranked history length: %d", got)
+	}
+
+	//a new context is seeded from the blend of the existing ones
+	casual := goat.Context("casual", svc.Config)
+	expected := rating.Average([]rating.Rating{goat.Rating(), goat.Context("ranked", svc.Config).Rating()})
+	if casual.Rating().Strength() != expected.Strength() {
+		t.Errorf("unexpected blended strength: %v", casual.Rating())
+	}
+}
+
+func TestPlayerContextNotApplied(t *testing.T) {
+	hooks := ratingutil.NewHooks()
+	hooks.OnBeforeApply(func(e *ratingutil.ApplyEvent) error {
+		if e.Context == "vetoed" {
+			return errors.New("vetoed")
+		}
+		return nil
+	})
+	svc := ratingutil.New(ratingutil.NewConfig().WithHooks(hooks))
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	match, _ := svc.NewMatchInContext("vetoed", sheep, goat)
+	match.Add(sheep, 1.0)
+	if err := svc.Apply(match); err == nil {
+		t.Fatal("vetoed apply should be error")
+	}
+	if keys := sheep.Contexts(); len(keys) != 0 {
+		t.Errorf("the context of the vetoed match is registered: %v", keys)
+	}
+
+	svc.Config.DefaultApplyStrategy = func(map[ratingutil.Element]rating.Rating, map[ratingutil.Element]float64) error {
+		return errors.New("failed")
+	}
+	match, _ = svc.NewMatchInContext("ranked", sheep, goat)
+	match.Add(sheep, 1.0)
+	if err := svc.Apply(match); err == nil {
+		t.Fatal("failed apply should be error")
+	}
+	if keys := goat.Contexts(); len(keys) != 0 {
+		t.Errorf("the context of the rolled back match is registered: %v", keys)
+	}
+}
+
+func TestPlayerContextFloor(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	svc := ratingutil.New(ratingutil.NewConfig().WithClock(clock).WithRatingPeriod(ratingutil.PeriodDay))
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	ranked := sheep.Context("ranked", svc.Config)
+	//the floor of a context is locked by the player of the default context, as Apply does
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			ranked.SetFloor(1400.0)
+			ranked.ClearFloor()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		match, _ := svc.NewMatchInContext("ranked", sheep, goat)
+		match.Add(goat, 1.0)
+		if err := svc.Apply(match); err != nil {
+			t.Fatal(err)
+		}
+		clock.now = clock.now.Add(ratingutil.PeriodDay + time.Hour)
+	}
+	wg.Wait()
+}
@@ -2,6 +2,9 @@ package ratingutil
 
 import (
 	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mashiike/rating"
@@ -17,10 +20,36 @@ type Element interface {
 }
 
 //Player is rating resouse
+//Match.Apply locks the players, so a player can be shared by the matches applied concurrently.
 type Player struct {
+	mu        sync.Mutex
+	seq       uint64
+	id        string
 	name      string
 	estimated *rating.Estimated
 	fixedAt   time.Time
+	floor     float64
+	hasFloor  bool
+	games     int
+	graduated bool
+	//advantage is the advantage on the Glicko-2 scale in the match being applied, it is set with the player locked.
+	advantage float64
+
+	//context is the key of the context, and parent is the player of the default context.
+	context    string
+	parent     *Player
+	contextsMu sync.Mutex
+	contexts   map[string]*Player
+	//removed are the contexts removed by UnmarshalBinary or a failed operation, a new context of the key reuses the player
+	//so that the callers holding it see the context again.
+	removed map[string]*Player
+}
+
+//playerSeq determines the lock ordering of players
+var playerSeq uint64
+
+func nextPlayerSeq() uint64 {
+	return atomic.AddUint64(&playerSeq, 1)
 }
 
 //Name is player name
@@ -28,22 +57,98 @@ func (p *Player) Name() string {
 	return p.name
 }
 
+//ID returns the unique identifier of the player, it is the key of the rating history.
+//If it is not set by WithID, it is the name.
+//Like the name, it is not changed after the player is shared, so it can be read without locking, such as in the hooks.
+func (p *Player) ID() string {
+	return p.root().historyKey()
+}
+
+//WithID is set the unique identifier of the player, such as the account id.
+//Set it when the names of the players are not unique, otherwise their rating history is mixed.
+//Call it right after the constructor, before the player is shared.
+func (p *Player) WithID(id string) *Player {
+	p.root().id = id
+	return p
+}
+
+func (p *Player) historyKey() string {
+	if p.id != "" {
+		return p.id
+	}
+	return p.name
+}
+
 //ApplyMatch reflects match results between players.
+//In Match.Apply, the advantage of the player set by Match.SetAdvantage is reflected in the expected score.
 func (p *Player) ApplyMatch(opponent rating.Rating, score float64) error {
+	if p.advantage != 0.0 {
+		opponent = rating.NewGlicko2(opponent.Mu()-p.advantage, opponent.Phi(), opponent.Sigma())
+	}
 	return p.estimated.ApplyMatch(opponent, score)
 }
 
 //Prepare does the work before operating the player according to the configs
+//Prepare is called by Match.Apply with the player locked. When calling it directly, do not share the player between goroutines.
 func (p *Player) Prepare(outcomeAt time.Time, config *Config) error {
 	for outcomeAt.Sub(p.fixedAt) > config.RatingPeriod {
-		if err := p.estimated.Fix(config.Tau); err != nil {
+		before := p.estimated.Fixed
+		var start time.Time
+		if config.Instrumentation != nil {
+			start = time.Now()
+		}
+		diagnostics, err := p.estimated.FixWithOptions(config.Tau, config.fixOptions(p))
+		if config.Instrumentation != nil {
+			config.Instrumentation.PeriodClosed(p.context, time.Since(start), diagnostics, err)
+		}
+		if err != nil {
 			return err
 		}
 		p.fixedAt = p.fixedAt.Add(config.RatingPeriod)
+		if err := p.record(config, HistoryPoint{
+			At:     p.fixedAt,
+			Kind:   PeriodClosed,
+			Rating: p.estimated.Fixed,
+		}); err != nil {
+			return err
+		}
+		config.fixed(&FixEvent{
+			Player: p,
+			At:     p.fixedAt,
+			Before: before,
+			After:  p.estimated.Fixed,
+		})
 	}
 	return nil
 }
 
+func (p *Player) record(config *Config, point HistoryPoint) error {
+	if config.History == nil {
+		return nil
+	}
+	point.Context = p.context
+	return errors.Wrapf(config.History.Record(p.root().historyKey(), point), "record history of %s", p.name)
+}
+
+//SetFloor sets the strength on the configured Scale that the player can not drop below at the end of the rating period.
+//It bounds the strength, not the display rating of TierSystem, use TierSystem.StrengthFloor to keep the player's tier.
+//It overrides Config.StrengthFloor.
+func (p *Player) SetFloor(strength float64) {
+	root := p.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	p.floor = strength
+	p.hasFloor = true
+}
+
+//ClearFloor removes the floor set by SetFloor
+func (p *Player) ClearFloor() {
+	root := p.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	p.hasFloor = false
+}
+
 //Rating returns the estimated strength of the current player
 func (p *Player) Rating() rating.Rating {
 	return p.estimated.Rating()
@@ -86,6 +191,13 @@ func (t *Team) Prepare(outcomeAt time.Time, config *Config) error {
 	return nil
 }
 
+//Members returns the team members
+func (t *Team) Members() Players {
+	ret := make(Players, len(t.members))
+	copy(ret, t.members)
+	return ret
+}
+
 //Rating return estimated team rating
 func (t *Team) Rating() rating.Rating {
 	ratings := make([]rating.Rating, 0, len(t.members))
@@ -104,3 +216,38 @@ func (t *Team) String() string {
 	str += " }"
 	return str
 }
+
+//playersOf returns the players that make up the element
+func playersOf(element Element) Players {
+	switch e := element.(type) {
+	case *Player:
+		return Players{e}
+	case *Team:
+		return e.members
+	}
+	return nil
+}
+
+//lockPlayers locks the players in a deterministic order to avoid deadlock, and returns the unlock function
+//The contexts of a player are locked by the player of the default context.
+func lockPlayers(players Players) func() {
+	seen := make(map[*Player]bool, len(players))
+	sorted := make(Players, 0, len(players))
+	for _, player := range players {
+		root := player.root()
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		sorted = append(sorted, root)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].seq < sorted[j].seq })
+	for _, player := range sorted {
+		player.mu.Lock()
+	}
+	return func() {
+		for i := len(sorted) - 1; i >= 0; i-- {
+			sorted[i].mu.Unlock()
+		}
+	}
+}
@@ -48,6 +48,6 @@ func ExampleService() {
 	//Output:
 	//[ bovidae:{ sheep:1700.0p-138.6 goat:1500.0p-700.0 }(0.66)  equidae:{ donkey:1400.0p-138.6 zebra:1500.0p-700.0 }(0.34) ]
 	//=== after match ===
-	//[ bovidae:{ sheep:1705.2p-137.2 goat:1654.5p-530.9 }(0.81)  equidae:{ donkey:1393.7p-137.0 zebra:1364.1p-536.3 }(0.19) ]
+	//[ bovidae:{ sheep:1705.2p-137.2 goat:1654.5p-531.0 }(0.81)  equidae:{ donkey:1393.7p-137.0 zebra:1364.1p-536.3 }(0.19) ]
 
 }
@@ -0,0 +1,291 @@
+package ratingutil
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/pkg/errors"
+)
+
+//HistoryKind is the reason why a HistoryPoint was recorded
+type HistoryKind int
+
+//HistoryKind constants
+const (
+	//PeriodClosed is recorded when the rating period is over and the rating is fixed
+	PeriodClosed HistoryKind = iota + 1
+	//MatchApplied is recorded when the result of a match is reflected in the estimated rating
+	MatchApplied
+)
+
+//String is implements fmt.Stringer
+func (k HistoryKind) String() string {
+	switch k {
+	case PeriodClosed:
+		return "period_closed"
+	case MatchApplied:
+		return "match_applied"
+	}
+	return "unknown"
+}
+
+//HistoryPoint is a point of the rating time series
+type HistoryPoint struct {
+	At      time.Time
+	Kind    HistoryKind
+	MatchID string
+	//Context is the key of the player context, the default context is empty.
+	Context string
+	Rating  rating.Rating
+	//Scale is the scale of the exports such as MarshalJSON, WriteCSV, Change and Peak.
+	//It is not recorded, Service.History sets the configured Scale. if zero, DefaultScale.
+	Scale rating.Scale
+}
+
+func (p HistoryPoint) scale() rating.Scale {
+	if p.Scale == (rating.Scale{}) {
+		return rating.DefaultScale
+	}
+	return p.Scale
+}
+
+type historyPointJSON struct {
+	At         time.Time `json:"at"`
+	Kind       string    `json:"kind"`
+	MatchID    string    `json:"match_id,omitempty"`
+	Context    string    `json:"context,omitempty"`
+	Strength   float64   `json:"strength"`
+	Deviation  float64   `json:"deviation"`
+	Volatility float64   `json:"volatility"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The rating is flattened to strength, deviation and volatility on the Scale for plotting.
+func (p HistoryPoint) MarshalJSON() ([]byte, error) {
+	scale := p.scale()
+	return json.Marshal(historyPointJSON{
+		At:         p.At,
+		Kind:       p.Kind.String(),
+		MatchID:    p.MatchID,
+		Context:    p.Context,
+		Strength:   scale.Strength(p.Rating),
+		Deviation:  scale.Deviation(p.Rating),
+		Volatility: p.Rating.Volatility(),
+	})
+}
+
+//HistoryStore is a storage of rating time series per player.
+//The key is Player.ID, the name of the player unless WithID is set.
+//The points may be recorded out of time order, such as by concurrent applies and late results,
+//and Series must return them in time order.
+//Replace replaces the points at or after from with the points, it is used by Ledger to correct the history.
+//Implementations must be safe for concurrent use.
+type HistoryStore interface {
+	Record(key string, point HistoryPoint) error
+	Series(key string) (RatingSeries, error)
+	Replace(key string, from time.Time, points []HistoryPoint) error
+}
+
+//MemoryHistory is an on-memory HistoryStore
+type MemoryHistory struct {
+	mu     sync.RWMutex
+	series map[string]RatingSeries
+}
+
+//NewMemoryHistory is constractor of *MemoryHistory
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{
+		series: make(map[string]RatingSeries),
+	}
+}
+
+//Record inserts a point to the player's series in time order, after the points of the same time
+func (h *MemoryHistory) Record(key string, point HistoryPoint) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	i := sort.Search(len(s), func(i int) bool { return s[i].At.After(point.At) })
+	s = append(s, HistoryPoint{})
+	copy(s[i+1:], s[i:])
+	s[i] = point
+	h.series[key] = s
+	return nil
+}
+
+//Replace replaces the points of the player's series at or after from with the points
+func (h *MemoryHistory) Replace(key string, from time.Time, points []HistoryPoint) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	i := sort.Search(len(s), func(i int) bool { return !s[i].At.Before(from) })
+	replaced := make(RatingSeries, i, i+len(points))
+	copy(replaced, s[:i])
+	replaced = append(replaced, points...)
+	sort.SliceStable(replaced[i:], func(a, b int) bool { return replaced[i+a].At.Before(replaced[i+b].At) })
+	h.series[key] = replaced
+	return nil
+}
+
+//Series returns copy of the player's series
+func (h *MemoryHistory) Series(key string) (RatingSeries, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	s, ok := h.series[key]
+	if !ok {
+		return nil, errors.Errorf("history of %s not found", key)
+	}
+	ret := make(RatingSeries, len(s))
+	copy(ret, s)
+	return ret, nil
+}
+
+//removeHistory removes the points from the series of each key, and saves the series before the removal to saved if not nil
+func removeHistory(history HistoryStore, stale map[string][]HistoryPoint, saved map[string]RatingSeries) error {
+	keys := make([]string, 0, len(stale))
+	for key := range stale {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		series, err := history.Series(key)
+		if err != nil {
+			return errors.Wrapf(err, "history of %s", key)
+		}
+		if _, ok := saved[key]; saved != nil && !ok {
+			saved[key] = series
+		}
+		from := stale[key][0].At
+		for _, point := range stale[key] {
+			if point.At.Before(from) {
+				from = point.At
+			}
+		}
+		kept := make([]HistoryPoint, 0, len(series))
+		for _, point := range series {
+			if point.At.Before(from) {
+				continue
+			}
+			if i := indexOfPoint(stale[key], point); i >= 0 {
+				stale[key] = append(stale[key][:i], stale[key][i+1:]...)
+				continue
+			}
+			kept = append(kept, point)
+		}
+		if err := history.Replace(key, from, kept); err != nil {
+			return errors.Wrapf(err, "history of %s", key)
+		}
+	}
+	return nil
+}
+
+func indexOfPoint(points []HistoryPoint, point HistoryPoint) int {
+	for i, p := range points {
+		if p.At.Equal(point.At) && p.Kind == point.Kind && p.MatchID == point.MatchID && p.Context == point.Context && p.Rating == point.Rating {
+			return i
+		}
+	}
+	return -1
+}
+
+//RatingSeries is time series of rating, ordered by recorded time
+type RatingSeries []HistoryPoint
+
+//At returns the rating at time t.
+//If t is before the first point, ok is false.
+func (s RatingSeries) At(t time.Time) (r rating.Rating, ok bool) {
+	i := sort.Search(len(s), func(i int) bool { return s[i].At.After(t) })
+	if i == 0 {
+		return rating.Rating{}, false
+	}
+	return s[i-1].Rating, true
+}
+
+//OnScale returns the series whose exports are on the scale.
+func (s RatingSeries) OnScale(scale rating.Scale) RatingSeries {
+	ret := make(RatingSeries, len(s))
+	for i, point := range s {
+		point.Scale = scale
+		ret[i] = point
+	}
+	return ret
+}
+
+//Peak returns the point with the highest strength.
+//If the series is empty, ok is false.
+func (s RatingSeries) Peak() (p HistoryPoint, ok bool) {
+	for i, point := range s {
+		if i == 0 || point.scale().Strength(point.Rating) > p.scale().Strength(p.Rating) {
+			p = point
+			ok = true
+		}
+	}
+	return p, ok
+}
+
+//InContext returns only the points of the player context.
+func (s RatingSeries) InContext(key string) RatingSeries {
+	ret := make(RatingSeries, 0, len(s))
+	for _, point := range s {
+		if point.Context == key {
+			ret = append(ret, point)
+		}
+	}
+	return ret
+}
+
+//Closed returns only the points recorded at the end of the rating periods.
+func (s RatingSeries) Closed() RatingSeries {
+	ret := make(RatingSeries, 0, len(s))
+	for _, point := range s {
+		if point.Kind == PeriodClosed {
+			ret = append(ret, point)
+		}
+	}
+	return ret
+}
+
+//Change returns the strength change over the last n rating periods.
+//If less than n+1 period closes are recorded, it is the change since the first close.
+func (s RatingSeries) Change(n int) float64 {
+	closed := s.Closed()
+	if len(closed) == 0 || n <= 0 {
+		return 0.0
+	}
+	from := len(closed) - 1 - n
+	if from < 0 {
+		from = 0
+	}
+	last, first := closed[len(closed)-1], closed[from]
+	return last.scale().Strength(last.Rating) - first.scale().Strength(first.Rating)
+}
+
+//WriteCSV writes the series as CSV with header line.
+func (s RatingSeries) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"at", "kind", "match_id", "context", "strength", "deviation", "volatility"}); err != nil {
+		return err
+	}
+	for _, point := range s {
+		scale := point.scale()
+		record := []string{
+			point.At.Format(time.RFC3339),
+			point.Kind.String(),
+			point.MatchID,
+			point.Context,
+			strconv.FormatFloat(scale.Strength(point.Rating), 'f', -1, 64),
+			strconv.FormatFloat(scale.Deviation(point.Rating), 'f', -1, 64),
+			strconv.FormatFloat(point.Rating.Volatility(), 'f', -1, 64),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,253 @@
+package ratingutil_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/mashiike/rating/ratingutil"
+)
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestHistory(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	svc := ratingutil.New(
+		ratingutil.NewConfig().
+			WithClock(clock).
+			WithRatingPeriod(ratingutil.PeriodDay).
+			WithHistory(ratingutil.NewMemoryHistory()),
+	)
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	for i := 0; i < 3; i++ {
+		clock.now = clock.now.Add(ratingutil.PeriodDay + time.Hour)
+		match, _ := svc.NewMatch(sheep, goat)
+		match.WithID("match-" + string(rune('a'+i)))
+		match.Add(sheep, 1.0)
+		if err := svc.Apply(match); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	series, err := svc.History("sheep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 6 {
+		t.Fatalf("unexpected series length: %d", len(series))
+	}
+	if series[1].Kind != ratingutil.MatchApplied || series[1].MatchID != "match-a" {
+		t.Errorf("unexpected first match point: %+v", series[1])
+	}
+	if got := len(series.Closed()); got != 3 {
+		t.Errorf("unexpected closed length: %d", got)
+	}
+	if _, ok := series.At(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)); ok {
+		t.Error("rating before first point should not be found")
+	}
+	if r, ok := series.At(clock.now); !ok || r != sheep.Rating() {
+		t.Errorf("unexpected rating at now: %v", r)
+	}
+	peak, _ := series.Peak()
+	if peak.Rating != sheep.Rating() {
+		t.Errorf("unexpected peak: %v", peak.Rating)
+	}
+	if got := series.Change(1); got <= 0.0 {
+		t.Errorf("winner strength change should be positive: %v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := series.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 7 {
+		t.Errorf("unexpected csv lines: %d\n%s", lines, buf.String())
+	}
+	b, err := json.Marshal(series)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"kind":"period_closed"`) {
+		t.Errorf("unexpected json: %s", b)
+	}
+}
+
+func TestMemoryHistoryOrder(t *testing.T) {
+	h := ratingutil.NewMemoryHistory()
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, hours := range []int{3, 1, 2, 1} {
+		r := rating.New(1500.0+float64(i)*10.0, 200.0, 0.06)
+		if err := h.Record("sheep", ratingutil.HistoryPoint{At: base.Add(time.Duration(hours) * time.Hour), Kind: ratingutil.MatchApplied, Rating: r}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	series, _ := h.Series("sheep")
+	for i := 1; i < len(series); i++ {
+		if series[i].At.Before(series[i-1].At) {
+			t.Fatalf("series is not in time order: %v", series)
+		}
+	}
+	//the later recorded point of the same time comes after
+	if r, _ := series.At(base.Add(time.Hour)); r != rating.New(1530.0, 200.0, 0.06) {
+		t.Errorf("unexpected rating at 1h: %v", r)
+	}
+	if r, _ := series.At(base.Add(2 * time.Hour)); r != rating.New(1520.0, 200.0, 0.06) {
+		t.Errorf("unexpected rating at 2h: %v", r)
+	}
+}
+
+func TestHistoryByID(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig().WithHistory(ratingutil.NewMemoryHistory()))
+	first := svc.NewDefaultPlayer("sheep").WithID("account-1")
+	second := svc.NewDefaultPlayer("sheep").WithID("account-2")
+	match, _ := svc.NewMatch(first, second)
+	match.Add(first, 1.0)
+	if err := svc.Apply(match); err != nil {
+		t.Fatal(err)
+	}
+	for _, player := range []*ratingutil.Player{first, second} {
+		series, err := svc.History(player.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(series) != 1 || series[0].Rating != player.Rating() {
+			t.Errorf("unexpected history of %s: %v", player.ID(), series)
+		}
+	}
+	if _, err := svc.History("sheep"); err == nil {
+		t.Error("history should be keyed by the id")
+	}
+	b, err := first.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ratingutil.Player
+	if err := decoded.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID() != "account-1" {
+		t.Errorf("unexpected decoded id: %s", decoded.ID())
+	}
+}
+
+//flakyHistory fails to record after the given number of points
+type flakyHistory struct {
+	*ratingutil.MemoryHistory
+	left int
+}
+
+func (h *flakyHistory) Record(key string, point ratingutil.HistoryPoint) error {
+	if h.left == 0 {
+		return errors.New("history is down")
+	}
+	h.left--
+	return h.MemoryHistory.Record(key, point)
+}
+
+func TestHistoryFlushFailure(t *testing.T) {
+	history := &flakyHistory{MemoryHistory: ratingutil.NewMemoryHistory(), left: 1}
+	hooks := ratingutil.NewHooks()
+	applied := 0
+	hooks.OnAfterApply(func(*ratingutil.ApplyEvent) { applied++ })
+	svc := ratingutil.New(ratingutil.NewConfig().WithHistory(history).WithHooks(hooks))
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	before := sheep.Rating()
+	match, _ := svc.NewMatch(sheep, goat)
+	match.Add(sheep, 1.0)
+	if err := svc.Apply(match); err == nil {
+		t.Fatal("apply with the failing history should fail")
+	}
+	if sheep.Rating() != before || goat.Rating() != before {
+		t.Errorf("ratings should be restored: %v %v", sheep.Rating(), goat.Rating())
+	}
+	if applied != 0 {
+		t.Errorf("after apply hook should not be called: %d", applied)
+	}
+	for _, name := range []string{"sheep", "goat"} {
+		if series, _ := history.Series(name); len(series) != 0 {
+			t.Errorf("recorded history of %s should be removed: %v", name, series)
+		}
+	}
+
+	//the scores are kept, so that the retry applies the match once
+	history.left = -1
+	if err := svc.Apply(match); err != nil {
+		t.Fatal(err)
+	}
+	if applied != 1 || sheep.Rating().Strength() <= goat.Rating().Strength() {
+		t.Errorf("unexpected retry: %d %v %v", applied, sheep.Rating(), goat.Rating())
+	}
+	for _, name := range []string{"sheep", "goat"} {
+		if series, _ := history.Series(name); len(series) != 1 {
+			t.Errorf("unexpected history of %s: %v", name, series)
+		}
+	}
+}
+
+func TestHistoryOnScale(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	scale := rating.NewScale(25.0, 25.0/3.0)
+	svc := ratingutil.New(
+		ratingutil.NewConfig().
+			WithClock(clock).
+			WithScale(scale).
+			WithRatingPeriod(ratingutil.PeriodDay).
+			WithHistory(ratingutil.NewMemoryHistory()),
+	)
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	for i := 0; i < 2; i++ {
+		clock.now = clock.now.Add(ratingutil.PeriodDay + time.Hour)
+		match, _ := svc.NewMatch(sheep, goat)
+		match.Add(sheep, 1.0)
+		if err := svc.Apply(match); err != nil {
+			t.Fatal(err)
+		}
+	}
+	series, err := svc.History("sheep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := series[len(series)-1]
+	if peak, _ := series.Peak(); scale.Strength(peak.Rating) != scale.Strength(sheep.Rating()) {
+		t.Errorf("unexpected peak: %v", peak.Rating)
+	}
+	closed := series.Closed()
+	if got, expected := series.Change(1), scale.Strength(closed[1].Rating)-scale.Strength(closed[0].Rating); got != expected {
+		t.Errorf("change should be on the scale: got %v, expected %v", got, expected)
+	}
+	b, err := json.Marshal(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Strength  float64 `json:"strength"`
+		Deviation float64 `json:"deviation"`
+	}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Strength != scale.Strength(last.Rating) || decoded.Deviation != scale.Deviation(last.Rating) {
+		t.Errorf("json should be on the scale: %s", b)
+	}
+	var buf bytes.Buffer
+	if err := series.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if expected := strconv.FormatFloat(scale.Strength(last.Rating), 'f', -1, 64); !strings.Contains(buf.String(), expected) {
+		t.Errorf("csv should be on the scale: %s", buf.String())
+	}
+}
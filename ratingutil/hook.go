@@ -0,0 +1,210 @@
+package ratingutil
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mashiike/rating"
+)
+
+//ApplyEvent is the event of Match.Apply
+type ApplyEvent struct {
+	Match   *Match
+	At      time.Time
+	Context string
+	Scores  map[Element]float64
+	//Before is the rating of each Team / Player before the match is applied.
+	Before map[Element]rating.Rating
+	//After is the rating of each Team / Player after the match is applied, nil in the hooks before apply.
+	After map[Element]rating.Rating
+}
+
+//FixEvent is the event of the end of the rating period of a player
+type FixEvent struct {
+	//Player is the player in the context that the period is closed.
+	Player *Player
+	//At is the end of the closed rating period.
+	At     time.Time
+	Before rating.Rating
+	After  rating.Rating
+}
+
+//RecomputeEvent is the event of a Ledger recomputing the players from a corrected result
+type RecomputeEvent struct {
+	//Players are the recomputed players.
+	Players Players
+	//From is the time of the earliest corrected result.
+	From time.Time
+}
+
+//Hooks is the registry of the functions called when ratings change.
+//The hooks before apply are called with the players of the match locked, and return an error to veto the match.
+//The other hooks are called after the players are unlocked, and only when the change is committed.
+type Hooks struct {
+	mu            sync.RWMutex
+	beforeApply   []func(*ApplyEvent) error
+	afterApply    []func(*ApplyEvent)
+	fix           []func(*FixEvent)
+	graduate      []func(*GraduationEvent)
+	playerCreated []func(*Player)
+	recompute     []func(*RecomputeEvent)
+}
+
+//NewHooks creates empty hooks
+func NewHooks() *Hooks {
+	return &Hooks{}
+}
+
+//OnBeforeApply registers the function called before a match is applied.
+//If it returns an error, the match is not applied and Apply returns the error, the scores are kept.
+//It must not apply a match nor change the players, because they are locked.
+func (h *Hooks) OnBeforeApply(fn func(*ApplyEvent) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beforeApply = append(h.beforeApply, fn)
+}
+
+//OnAfterApply registers the function called after a match is applied
+func (h *Hooks) OnAfterApply(fn func(*ApplyEvent)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.afterApply = append(h.afterApply, fn)
+}
+
+//OnFix registers the function called at the end of the rating period of each player
+func (h *Hooks) OnFix(fn func(*FixEvent)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fix = append(h.fix, fn)
+}
+
+//OnGraduate registers the function called when a player leaves the provisional phase
+func (h *Hooks) OnGraduate(fn func(*GraduationEvent)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.graduate = append(h.graduate, fn)
+}
+
+//OnPlayerCreated registers the function called when Service creates a player
+func (h *Hooks) OnPlayerCreated(fn func(*Player)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.playerCreated = append(h.playerCreated, fn)
+}
+
+//OnRecompute registers the function called when a Ledger recomputes players,
+//instead of the hooks of each recomputed result.
+func (h *Hooks) OnRecompute(fn func(*RecomputeEvent)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.recompute = append(h.recompute, fn)
+}
+
+func (h *Hooks) fireBeforeApply(e *ApplyEvent) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, fn := range h.beforeApply {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Hooks) fireAfterApply(e *ApplyEvent) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, fn := range h.afterApply {
+		fn(e)
+	}
+}
+
+func (h *Hooks) fireFix(e *FixEvent) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, fn := range h.fix {
+		fn(e)
+	}
+}
+
+func (h *Hooks) fireGraduate(e *GraduationEvent) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, fn := range h.graduate {
+		fn(e)
+	}
+}
+
+func (h *Hooks) firePlayerCreated(p *Player) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, fn := range h.playerCreated {
+		fn(p)
+	}
+}
+
+func (h *Hooks) fireRecompute(e *RecomputeEvent) {
+	if h == nil || e == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, fn := range h.recompute {
+		fn(e)
+	}
+}
+
+//hasApply reports whether there are any hooks of apply, to skip building the events
+func (h *Hooks) hasApply() bool {
+	if h == nil {
+		return false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.beforeApply) > 0 || len(h.afterApply) > 0
+}
+
+//eventBuffer holds the events until the transaction is committed
+type eventBuffer struct {
+	fixes       []*FixEvent
+	graduations []*GraduationEvent
+}
+
+//fixed notifies the end of the rating period, it is buffered in a transaction
+func (c *Config) fixed(e *FixEvent) {
+	if c.Hooks == nil {
+		return
+	}
+	if c.events != nil {
+		c.events.fixes = append(c.events.fixes, e)
+		return
+	}
+	c.Hooks.fireFix(e)
+}
+
+//graduated notifies the end of the provisional phase, it is buffered in a transaction
+func (c *Config) graduated(e *GraduationEvent) {
+	if c.Hooks == nil {
+		return
+	}
+	if c.events != nil {
+		c.events.graduations = append(c.events.graduations, e)
+		return
+	}
+	c.Hooks.fireGraduate(e)
+}
@@ -0,0 +1,191 @@
+package ratingutil_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/mashiike/rating/ratingutil"
+)
+
+func TestHooks(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	svc := ratingutil.New(ratingutil.NewConfig().WithClock(clock).WithRatingPeriod(ratingutil.PeriodDay))
+
+	var created []string
+	var fixes []*ratingutil.FixEvent
+	var applied []*ratingutil.ApplyEvent
+	veto := errors.New("banned")
+	svc.Hooks.OnPlayerCreated(func(p *ratingutil.Player) {
+		created = append(created, p.Name())
+	})
+	svc.Hooks.OnBeforeApply(func(e *ratingutil.ApplyEvent) error {
+		for element := range e.Scores {
+			if element.Name() == "cheater" {
+				return veto
+			}
+		}
+		return nil
+	})
+	svc.Hooks.OnAfterApply(func(e *ratingutil.ApplyEvent) {
+		applied = append(applied, e)
+	})
+	svc.Hooks.OnFix(func(e *ratingutil.FixEvent) {
+		// the players are unlocked in the hooks after apply
+		e.Player.SetFloor(0.0)
+		fixes = append(fixes, e)
+	})
+
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	cheater := svc.NewDefaultPlayer("cheater")
+	if len(created) != 3 {
+		t.Errorf("created = %v", created)
+	}
+
+	match, _ := svc.NewMatch(sheep, cheater)
+	match.Add(cheater, 1.0)
+	if err := svc.Apply(match); !errors.Is(err, veto) {
+		t.Errorf("err = %v, want veto", err)
+	}
+	if match.Scores()[cheater] != 1.0 {
+		t.Error("vetoed match scores are reset")
+	}
+	if len(applied) != 0 {
+		t.Errorf("vetoed match is applied: %v", applied)
+	}
+
+	clock.now = clock.now.Add(ratingutil.PeriodDay + time.Hour)
+	before := sheep.Rating()
+	match, _ = svc.NewMatch(sheep, goat)
+	match.Add(sheep, 1.0)
+	if err := svc.Apply(match); err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 {
+		t.Fatalf("applied = %d events", len(applied))
+	}
+	e := applied[0]
+	if e.Scores[sheep] != 1.0 || e.Scores[goat] != 0.0 {
+		t.Errorf("scores = %v", e.Scores)
+	}
+	if e.Before[sheep] != before || e.After[sheep] != sheep.Rating() {
+		t.Errorf("before = %v, after = %v", e.Before[sheep], e.After[sheep])
+	}
+	if e.After[sheep].Strength() <= e.Before[sheep].Strength() {
+		t.Errorf("winner strength is not increased: %v -> %v", e.Before[sheep], e.After[sheep])
+	}
+	if len(fixes) != 2 {
+		t.Fatalf("fixes = %d events", len(fixes))
+	}
+	for _, fix := range fixes {
+		if fix.At != time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC) {
+			t.Errorf("fix at %v", fix.At)
+		}
+	}
+}
+
+func TestHooksRollback(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	svc := ratingutil.New(ratingutil.NewConfig().WithClock(clock).WithRatingPeriod(ratingutil.PeriodDay))
+	fixes := 0
+	svc.Hooks.OnFix(func(e *ratingutil.FixEvent) {
+		fixes++
+	})
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	svc.Config.WithApplyStrategy(func(ratings map[ratingutil.Element]rating.Rating, scores map[ratingutil.Element]float64) error {
+		return errors.New("broken strategy")
+	})
+	clock.now = clock.now.Add(ratingutil.PeriodDay + time.Hour)
+	match, _ := svc.NewMatch(sheep, goat)
+	match.Add(sheep, 1.0)
+	if err := svc.Apply(match); err == nil {
+		t.Fatal("apply should fail")
+	}
+	if fixes != 0 {
+		t.Errorf("fix events of rolled back apply = %d", fixes)
+	}
+}
+
+func TestHooksReadMatch(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig())
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	match, _ := svc.NewMatch(sheep, goat)
+	var before, after map[ratingutil.Element]float64
+	svc.Hooks.OnBeforeApply(func(e *ratingutil.ApplyEvent) error {
+		before = e.Match.Scores()
+		_ = e.Match.Ratings()
+		_ = e.Match.WinProbs()
+		_ = e.Match.String()
+		_ = sheep.ID()
+		return nil
+	})
+	svc.Hooks.OnAfterApply(func(e *ratingutil.ApplyEvent) {
+		after = e.Match.Scores()
+		_ = match.String()
+	})
+
+	for _, retain := range []bool{false, true} {
+		if retain {
+			svc.RetainResults()
+		}
+		match.Add(sheep, 1.0)
+		done := make(chan error, 1)
+		go func() {
+			done <- svc.Apply(match)
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("retain %v: the hooks reading the match are deadlocked", retain)
+		}
+		if before[sheep] != 1.0 || after[sheep] != 0.0 {
+			t.Errorf("retain %v: unexpected scores in the hooks: before %v, after %v", retain, before, after)
+		}
+		if got := match.Scores()[sheep]; got != 0.0 {
+			t.Errorf("retain %v: the applied score is kept: %v", retain, got)
+		}
+	}
+}
+
+func TestHooksRetainedMatch(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig())
+	ledger := svc.RetainResults()
+	red := svc.NewTeam("red", ratingutil.Players{svc.NewDefaultPlayer("sheep"), svc.NewDefaultPlayer("goat")})
+	blue := svc.NewTeam("blue", ratingutil.Players{svc.NewDefaultPlayer("wolf"), svc.NewDefaultPlayer("fox")})
+	match, _ := svc.NewMatch(red, blue)
+	var before, after *ratingutil.ApplyEvent
+	svc.Hooks.OnBeforeApply(func(e *ratingutil.ApplyEvent) error {
+		before = e
+		return nil
+	})
+	svc.Hooks.OnAfterApply(func(e *ratingutil.ApplyEvent) {
+		after = e
+		// the ledger is unlocked in the hooks after apply
+		_, _ = ledger.Player("sheep")
+	})
+	match.Add(red, 1.0)
+	if err := svc.Apply(match); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []*ratingutil.ApplyEvent{before, after} {
+		if e == nil || e.Match != match {
+			t.Fatalf("the event should have the caller's match: %+v", e)
+		}
+		if e.Scores[red] != 1.0 || len(e.Scores) != 2 {
+			t.Errorf("the scores should be keyed by the caller's teams: %v", e.Scores)
+		}
+		if _, ok := e.Before[blue]; !ok {
+			t.Errorf("the ratings before should be keyed by the caller's teams: %v", e.Before)
+		}
+	}
+	if after.After[red] != red.Rating() || after.After[blue] != blue.Rating() {
+		t.Errorf("the ratings after should be keyed by the caller's teams: %v", after.After)
+	}
+}
@@ -0,0 +1,34 @@
+package ratingutil
+
+import (
+	"time"
+
+	"github.com/mashiike/rating"
+)
+
+//Instrumentation receives the measurements of Service operations, for metrics and tracing.
+//Config.Instrumentation is nil by default, then nothing is measured.
+//The methods are called concurrently from the goroutines applying matches.
+type Instrumentation interface {
+	//MatchApplied is called when Match.Apply ends, err is nil on success.
+	MatchApplied(context string, elapsed time.Duration, err error)
+	//PeriodClosed is called when the rating period of a player is closed, with the diagnostics of the volatility iteration.
+	PeriodClosed(context string, elapsed time.Duration, diagnostics rating.Diagnostics, err error)
+	//RatingChanged is called for each player of an applied match, with the strength change on the configured Scale.
+	RatingChanged(context string, delta float64)
+}
+
+//WithInstrumentation is set Instrumentation to config
+func (c *Config) WithInstrumentation(instrumentation Instrumentation) *Config {
+	c.Instrumentation = instrumentation
+	return c
+}
+
+//strengths returns the strength of the players on the configured Scale
+func (c *Config) strengths(players Players) []float64 {
+	strengths := make([]float64, len(players))
+	for i, player := range players {
+		strengths[i] = c.Scale.Strength(player.Rating())
+	}
+	return strengths
+}
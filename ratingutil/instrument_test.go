@@ -0,0 +1,63 @@
+package ratingutil_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mashiike/rating/ratingutil"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	metrics := ratingutil.NewPrometheusMetrics()
+	svc := ratingutil.New(
+		ratingutil.NewConfig().
+			WithClock(clock).
+			WithRatingPeriod(ratingutil.PeriodDay).
+			WithInstrumentation(metrics),
+	)
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	for i := 0; i < 2; i++ {
+		clock.now = clock.now.Add(ratingutil.PeriodDay + time.Hour)
+		match, _ := svc.NewMatchInContext("ranked", sheep, goat)
+		match.Add(sheep, 1.0)
+		if err := svc.Apply(match); err != nil {
+			t.Fatal(err)
+		}
+	}
+	match, _ := svc.NewMatchInContext("ranked", sheep, goat)
+	match.Add(sheep, 3.0)
+	if err := svc.Apply(match); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`rating_matches_applied_total{context="ranked",result="success"} 3`,
+		"# TYPE rating_apply_duration_seconds histogram",
+		"rating_apply_duration_seconds_count 3",
+		"rating_fix_duration_seconds_count 4",
+		"rating_fix_solver_iterations_count 4",
+		"rating_fix_errors_total 0",
+		"rating_strength_delta_count 6",
+		`rating_strength_delta_bucket{le="+Inf"} 6`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q\n%s", want, body)
+		}
+	}
+	metrics.MatchApplied("ranké\t\"x\"\\\n", time.Millisecond, nil)
+	rec = httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `rating_matches_applied_total{context="ranké` + "\t" + `\"x\"\\\n",result="success"} 1`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("metrics do not contain %q\n%s", want, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("content type = %s", ct)
+	}
+}
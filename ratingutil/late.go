@@ -0,0 +1,207 @@
+package ratingutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//LatePolicy decides how to handle a result whose time falls in an already closed rating period of a player
+type LatePolicy int
+
+//LatePolicy constants
+const (
+	//ApplyLateToCurrent counts a late result in the current rating period. It is the default.
+	ApplyLateToCurrent LatePolicy = iota
+	//RejectLate rejects a late result with *LateResultError.
+	RejectLate
+	//ReopenLate reopens the closed rating period and recomputes forward from the results retained by Service.RetainResults.
+	//Without the retained results, or if they do not reach back to the closed period, a late result is rejected.
+	ReopenLate
+)
+
+//String is implements fmt.Stringer
+func (p LatePolicy) String() string {
+	switch p {
+	case ApplyLateToCurrent:
+		return "apply_to_current"
+	case RejectLate:
+		return "reject"
+	case ReopenLate:
+		return "reopen"
+	}
+	return "unknown"
+}
+
+//LateResultError is the error of a result in an already closed rating period
+type LateResultError struct {
+	Player string
+	At     time.Time
+	//PeriodStart is the start of the current rating period of the player.
+	PeriodStart time.Time
+}
+
+//Error returns the string representation of a LateResultError.
+func (e *LateResultError) Error() string {
+	return fmt.Sprintf("late result at %v for %s, the rating period is closed until %v", e.At, e.Player, e.PeriodStart)
+}
+
+//WithLatePolicy is set LatePolicy to config
+func (c *Config) WithLatePolicy(policy LatePolicy) *Config {
+	c.LatePolicy = policy
+	return c
+}
+
+//checkLate returns *LateResultError if the result at outcomeAt is late for any of the players, and the policy does not accept it.
+//The players must be locked.
+func (c *Config) checkLate(players Players, outcomeAt time.Time) error {
+	if c.LatePolicy == ApplyLateToCurrent {
+		return nil
+	}
+	return late(players, outcomeAt)
+}
+
+func late(players Players, outcomeAt time.Time) error {
+	for _, player := range players {
+		if outcomeAt.Before(player.fixedAt) {
+			return &LateResultError{Player: player.Name(), At: outcomeAt, PeriodStart: player.fixedAt}
+		}
+	}
+	return nil
+}
+
+//RetainResults makes the service keep the applied results in a Ledger, and returns it.
+//Then Service.Apply records the results through the Ledger, and LatePolicy ReopenLate recomputes the players from the late result.
+//The players are identified by Player.ID in the Ledger.
+func (s *Service) RetainResults() *Ledger {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+	if s.results == nil {
+		s.results = &Ledger{
+			service: s,
+			players: make(map[string]*Player),
+		}
+	}
+	return s.results
+}
+
+func (s *Service) retained() *Ledger {
+	s.resultsMu.Lock()
+	defer s.resultsMu.Unlock()
+	return s.results
+}
+
+//recordMatch records the match as a result, handling the late result by the policy.
+//The match scores are reset on success.
+func (l *Ledger) recordMatch(match *Match, outcomeAt time.Time) error {
+	match.applyMu.Lock()
+	defer match.applyMu.Unlock()
+	scores, advantages := match.snapshot()
+	origin := &ledgerOrigin{match: match}
+	l.mu.Lock()
+	event, err := l.recordScores(origin, scores, advantages, outcomeAt)
+	l.mu.Unlock()
+	l.service.Config.Hooks.fireRecompute(event)
+	if err != nil {
+		return err
+	}
+	match.consume(scores)
+	origin.fire()
+	return nil
+}
+
+func (l *Ledger) recordScores(origin *ledgerOrigin, scores, advantages map[Element]float64, outcomeAt time.Time) (*RecomputeEvent, error) {
+	match := origin.match
+	result := Result{
+		ID:      match.id,
+		At:      outcomeAt,
+		Context: match.context,
+	}
+	var players Players
+	ids := make(map[string]*Player)
+	for element, score := range scores {
+		side := Side{Score: score, Advantage: advantages[element]}
+		members := playersOf(element)
+		if members == nil {
+			return nil, errors.Errorf("element %s is neither a player nor a team", element.Name())
+		}
+		for _, member := range members {
+			root := member.root()
+			id := root.ID()
+			if err := l.checkID(id, root); err != nil {
+				return nil, err
+			}
+			if _, ok := ids[id]; ok {
+				return nil, errors.Errorf("player %s is in the match twice", id)
+			}
+			ids[id] = root
+			side.Players = append(side.Players, id)
+			players = append(players, root)
+		}
+		result.Sides = append(result.Sides, side)
+		origin.elements = append(origin.elements, element)
+	}
+	//the map order of the scores is random, the sides are ordered for the replay to be deterministic
+	sortSides(result.Sides, origin.elements)
+
+	unlock := lockPlayers(players)
+	contexts := make(Players, 0, len(players))
+	for _, player := range players {
+		contexts = append(contexts, player.lookupContext(match.context, l.service.Config))
+	}
+	err := late(contexts, outcomeAt)
+	unlock()
+	entry := &ledgerEntry{result: result, at: result.At, origin: origin}
+	if err != nil {
+		switch l.service.Config.LatePolicy {
+		case RejectLate:
+			return nil, err
+		case ApplyLateToCurrent:
+			//the players count it in the current period, it is ordered after the last result and keeps its time
+			entry.current = true
+			if n := len(l.entries); n > 0 && entry.at.Before(l.entries[n-1].at) {
+				entry.at = l.entries[n-1].at
+			}
+		}
+	}
+	var added []string
+	for id, player := range ids {
+		if _, ok := l.players[id]; !ok {
+			l.players[id] = player
+			added = append(added, id)
+		}
+	}
+	event, err := l.record(entry)
+	if err != nil {
+		for _, id := range added {
+			delete(l.players, id)
+		}
+	}
+	return event, err
+}
+
+//sortSides sorts the sides and the elements of the sides together
+func sortSides(sides []Side, elements []Element) {
+	sort.Sort(sidesByPlayers{sides: sides, elements: elements})
+}
+
+type sidesByPlayers struct {
+	sides    []Side
+	elements []Element
+}
+
+func (s sidesByPlayers) Len() int {
+	return len(s.sides)
+}
+
+func (s sidesByPlayers) Less(i, j int) bool {
+	return strings.Join(s.sides[i].Players, ",") < strings.Join(s.sides[j].Players, ",")
+}
+
+func (s sidesByPlayers) Swap(i, j int) {
+	s.sides[i], s.sides[j] = s.sides[j], s.sides[i]
+	s.elements[i], s.elements[j] = s.elements[j], s.elements[i]
+}
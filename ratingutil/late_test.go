@@ -0,0 +1,156 @@
+package ratingutil_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/mashiike/rating/ratingutil"
+)
+
+func TestLatePolicy(t *testing.T) {
+	day := func(n int) time.Time {
+		return ledgerStart.Add(time.Duration(n)*ratingutil.PeriodDay + time.Hour)
+	}
+	for _, c := range []struct {
+		policy  ratingutil.LatePolicy
+		rejects bool
+	}{
+		{ratingutil.ApplyLateToCurrent, false},
+		{ratingutil.RejectLate, true},
+		//without the retained results, the period can not be reopened
+		{ratingutil.ReopenLate, true},
+	} {
+		t.Run(c.policy.String(), func(t *testing.T) {
+			svc := ratingutil.New(ratingutil.NewConfig().WithRatingPeriod(ratingutil.PeriodDay).WithLatePolicy(c.policy))
+			sheep := svc.NewPlayer("sheep", rating.Default(0.06), ledgerStart)
+			goat := svc.NewPlayer("goat", rating.Default(0.06), ledgerStart)
+			match, _ := svc.NewMatch(sheep, goat)
+			match.Add(sheep, 1.0)
+			if err := svc.ApplyWithTime(match, day(2)); err != nil {
+				t.Fatal(err)
+			}
+			match.Add(goat, 1.0)
+			err := svc.ApplyWithTime(match, day(0))
+			var lateErr *ratingutil.LateResultError
+			if got := errors.As(err, &lateErr); got != c.rejects {
+				t.Fatalf("err = %v", err)
+			}
+			if c.rejects && match.Scores()[goat] != 1.0 {
+				t.Error("scores of the rejected result are reset")
+			}
+		})
+	}
+}
+
+func TestLatePolicyReopen(t *testing.T) {
+	results := ledgerResults()
+	newService := func() *ratingutil.Service {
+		svc := ratingutil.New(ratingutil.NewConfig().WithRatingPeriod(ratingutil.PeriodDay).WithLatePolicy(ratingutil.ReopenLate))
+		svc.RetainResults()
+		return svc
+	}
+	apply := func(svc *ratingutil.Service, players map[string]*ratingutil.Player, r ratingutil.Result) {
+		t.Helper()
+		match, _ := svc.NewMatch(players[r.Sides[0].Players[0]], players[r.Sides[1].Players[0]])
+		match.WithID(r.ID)
+		match.Add(players[r.Sides[0].Players[0]], r.Sides[0].Score)
+		if err := svc.ApplyWithTime(match, r.At); err != nil {
+			t.Fatal(err)
+		}
+		if match.Scores()[players[r.Sides[0].Players[0]]] != 0.0 {
+			t.Error("scores are not reset")
+		}
+	}
+	newPlayers := func(svc *ratingutil.Service) map[string]*ratingutil.Player {
+		players := make(map[string]*ratingutil.Player)
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			players[name] = svc.NewPlayer(name, rating.Default(0.06), ledgerStart)
+		}
+		return players
+	}
+
+	inOrder := newService()
+	want := newPlayers(inOrder)
+	for _, r := range results {
+		apply(inOrder, want, r)
+	}
+
+	//r3 of day 1 arrives after the results of day 3
+	late := newService()
+	got := newPlayers(late)
+	for _, r := range results {
+		if r.ID != "r3" {
+			apply(late, got, r)
+		}
+	}
+	apply(late, got, results[2])
+
+	for name := range want {
+		if got[name].Rating().Format(rating.DefaultFormat) != want[name].Rating().Format(rating.DefaultFormat) {
+			t.Errorf("%s = %v, want %v", name, got[name].Rating(), want[name].Rating())
+		}
+	}
+	if ids := late.RetainResults().Results(); len(ids) != 6 || ids[2].ID != "r3" {
+		t.Errorf("results = %v", ids)
+	}
+}
+
+func TestLatePolicyRetained(t *testing.T) {
+	day := func(n int) time.Time {
+		return ledgerStart.Add(time.Duration(n)*ratingutil.PeriodDay + time.Hour)
+	}
+	t.Run("apply_to_current", func(t *testing.T) {
+		svc := ratingutil.New(ratingutil.NewConfig().WithRatingPeriod(ratingutil.PeriodDay))
+		results := svc.RetainResults()
+		sheep := svc.NewPlayer("sheep", rating.Default(0.06), ledgerStart)
+		goat := svc.NewPlayer("goat", rating.Default(0.06), ledgerStart)
+		match, _ := svc.NewMatch(sheep, goat)
+		match.WithID("m1")
+		match.Add(sheep, 1.0)
+		if err := svc.ApplyWithTime(match, day(2)); err != nil {
+			t.Fatal(err)
+		}
+		late, _ := svc.NewMatch(sheep, goat)
+		late.WithID("m2")
+		late.Add(goat, 1.0)
+		if err := svc.ApplyWithTime(late, day(0)); err != nil {
+			t.Fatal(err)
+		}
+		//the late result keeps its time, and follows the results before it
+		got := results.Results()
+		if len(got) != 2 || got[1].ID != "m2" || !got[1].At.Equal(day(0)) {
+			t.Errorf("results = %v", got)
+		}
+	})
+	t.Run("reopen_out_of_reach", func(t *testing.T) {
+		svc := ratingutil.New(ratingutil.NewConfig().WithRatingPeriod(ratingutil.PeriodDay).WithLatePolicy(ratingutil.ReopenLate))
+		sheep := svc.NewPlayer("sheep", rating.Default(0.06), ledgerStart)
+		goat := svc.NewPlayer("goat", rating.Default(0.06), ledgerStart)
+		//the players roll their periods before the results are retained
+		match, _ := svc.NewMatch(sheep, goat)
+		match.Add(sheep, 1.0)
+		if err := svc.ApplyWithTime(match, day(2)); err != nil {
+			t.Fatal(err)
+		}
+		results := svc.RetainResults()
+		match.Add(sheep, 1.0)
+		if err := svc.ApplyWithTime(match, day(3)); err != nil {
+			t.Fatal(err)
+		}
+		before := sheep.Rating()
+		match.Add(goat, 1.0)
+		err := svc.ApplyWithTime(match, day(0))
+		var lateErr *ratingutil.LateResultError
+		if !errors.As(err, &lateErr) {
+			t.Fatalf("err = %v", err)
+		}
+		if sheep.Rating() != before || len(results.Results()) != 1 {
+			t.Errorf("the rejected result is applied: %v, %v", sheep.Rating(), results.Results())
+		}
+		if match.Scores()[goat] != 1.0 {
+			t.Error("scores of the rejected result are reset")
+		}
+	})
+}
@@ -0,0 +1,597 @@
+package ratingutil
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/pkg/errors"
+)
+
+//Result is a match result kept in the Ledger
+type Result struct {
+	ID      string
+	At      time.Time
+	Context string
+	Sides   []Side
+}
+
+//Side is a Team / Player of a Result
+type Side struct {
+	//Players are the ids of the players by Player.ID, a side of multiple players is a team.
+	Players   []string
+	Score     float64
+	Advantage float64
+}
+
+//players returns the ids of all players of the result
+func (r Result) players() []string {
+	var names []string
+	for _, side := range r.Sides {
+		names = append(names, side.Players...)
+	}
+	return names
+}
+
+func (r Result) involves(names map[string]bool) bool {
+	for _, name := range r.players() {
+		if names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+//Ledger is the log of the match results applied to the players, to correct the past results.
+//When a result is invalidated, the affected players are restored to the state before their first affected result,
+//and the rest of the results are applied again in time order. Only the players who played the affected players,
+//directly or through other players, are recomputed.
+//The history points of the recomputed results are replaced with the recomputed ones.
+//The hooks and the instrumentation are not called for each recomputed result, Hooks.OnRecompute is called once instead.
+//The hooks of a recorded result are called after the ledger is unlocked, and the events of Service.Apply have the caller's match and Team / Player.
+type Ledger struct {
+	mu      sync.Mutex
+	service *Service
+	players map[string]*Player
+	entries []*ledgerEntry
+}
+
+type ledgerEntry struct {
+	result Result
+	void   bool
+	//at is the time to order the entries. A late result counted in the current rating period by ApplyLateToCurrent
+	//is marked as current, and is ordered after the results recorded before it.
+	at      time.Time
+	current bool
+	//checkpoints are the binary form of the players before the result by the id
+	checkpoints map[string][]byte
+	//points are the history points recorded by applying the result
+	points []ledgerPoint
+	//origin is the caller recording the result, it is nil after the result is applied
+	origin *ledgerOrigin
+}
+
+//ledgerOrigin is the caller recording a result. The hooks of applying the result are deferred until the ledger is unlocked,
+//and the events are passed with the caller's match and Team / Player instead of the ones rebuilt from the result.
+type ledgerOrigin struct {
+	//match is the caller's match, nil for Ledger.Record
+	match *Match
+	//elements are the caller's Team / Player of each side of the result
+	elements []Element
+	pending  []func()
+}
+
+//hooks returns the hooks of applying the result of the elements rebuilt from it
+func (o *ledgerOrigin) hooks(base *Hooks, elements []Element) *Hooks {
+	if base == nil {
+		return nil
+	}
+	callers := make(map[Element]Element, len(elements))
+	if o.match != nil {
+		for i, element := range elements {
+			callers[element] = o.elements[i]
+		}
+	}
+	hooks := NewHooks()
+	if base.hasApply() {
+		hooks.OnBeforeApply(func(e *ApplyEvent) error {
+			o.translate(e, callers)
+			return base.fireBeforeApply(e)
+		})
+		hooks.OnAfterApply(func(e *ApplyEvent) {
+			o.translate(e, callers)
+			o.pending = append(o.pending, func() { base.fireAfterApply(e) })
+		})
+	}
+	hooks.OnFix(func(e *FixEvent) {
+		o.pending = append(o.pending, func() { base.fireFix(e) })
+	})
+	hooks.OnGraduate(func(e *GraduationEvent) {
+		o.pending = append(o.pending, func() { base.fireGraduate(e) })
+	})
+	return hooks
+}
+
+//translate replaces the match and the elements of the event with the caller's ones
+func (o *ledgerOrigin) translate(e *ApplyEvent, callers map[Element]Element) {
+	if o.match == nil {
+		return
+	}
+	e.Match = o.match
+	caller := func(element Element) Element {
+		if c, ok := callers[element]; ok {
+			return c
+		}
+		return element
+	}
+	scores := make(map[Element]float64, len(e.Scores))
+	for element, score := range e.Scores {
+		scores[caller(element)] = score
+	}
+	e.Scores = scores
+	before := make(map[Element]rating.Rating, len(e.Before))
+	for element, r := range e.Before {
+		before[caller(element)] = r
+	}
+	e.Before = before
+	if e.After != nil {
+		after := make(map[Element]rating.Rating, len(e.After))
+		for element, r := range e.After {
+			after[caller(element)] = r
+		}
+		e.After = after
+	}
+}
+
+//fire calls the deferred hooks, it must be called after the ledger is unlocked
+func (o *ledgerOrigin) fire() {
+	for _, fn := range o.pending {
+		fn()
+	}
+	o.pending = nil
+}
+
+type ledgerPoint struct {
+	key   string
+	point HistoryPoint
+}
+
+//pointRecorder keeps the history points recorded by applying a result
+type pointRecorder struct {
+	HistoryStore
+	points []ledgerPoint
+}
+
+func (r *pointRecorder) Record(key string, point HistoryPoint) error {
+	if err := r.HistoryStore.Record(key, point); err != nil {
+		return err
+	}
+	r.points = append(r.points, ledgerPoint{key: key, point: point})
+	return nil
+}
+
+//NewLedger creates a Ledger of the players
+func (s *Service) NewLedger(players ...*Player) (*Ledger, error) {
+	l := &Ledger{
+		service: s,
+		players: make(map[string]*Player, len(players)),
+	}
+	if err := l.Add(players...); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+//Add adds players to the ledger, the players are identified by Player.ID.
+//If another player of the same id is in the ledger, no players are added and Add returns an error.
+func (l *Ledger) Add(players ...*Player) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	added := make(map[string]*Player, len(players))
+	for _, player := range players {
+		player = player.root()
+		id := player.ID()
+		if err := l.checkID(id, player); err != nil {
+			return err
+		}
+		if other, ok := added[id]; ok && other != player {
+			return errors.Errorf("another player of id %s is added", id)
+		}
+		added[id] = player
+	}
+	for id, player := range added {
+		l.players[id] = player
+	}
+	return nil
+}
+
+//checkID returns an error if another player of the id is in the ledger
+func (l *Ledger) checkID(id string, player *Player) error {
+	if registered, ok := l.players[id]; ok && registered != player {
+		return errors.Errorf("another player of id %s is in the ledger", id)
+	}
+	return nil
+}
+
+//Player returns the player of the id
+func (l *Ledger) Player(id string) (*Player, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, ok := l.players[id]
+	return p, ok
+}
+
+//Results returns the valid results in the order of the log, that is the time order
+//except for the late results counted in the current rating period, which follow the results recorded before them.
+func (l *Ledger) Results() []Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	results := make([]Result, 0, len(l.entries))
+	for _, entry := range l.entries {
+		if !entry.void {
+			results = append(results, entry.result)
+		}
+	}
+	return results
+}
+
+//Record applies the result to the players and keeps it.
+//A result before the last result is inserted in time order, and the affected players are recomputed from it.
+//If the rating period of a player is closed before the result and the ledger does not reach back to it,
+//Record returns *LateResultError.
+func (l *Ledger) Record(result Result) error {
+	origin := &ledgerOrigin{}
+	l.mu.Lock()
+	event, err := l.record(&ledgerEntry{result: result, at: result.At, origin: origin})
+	l.mu.Unlock()
+	l.service.Config.Hooks.fireRecompute(event)
+	if err != nil {
+		return err
+	}
+	origin.fire()
+	return nil
+}
+
+func (l *Ledger) record(entry *ledgerEntry) (*RecomputeEvent, error) {
+	i := sort.Search(len(l.entries), func(i int) bool {
+		return l.entries[i].at.After(entry.at)
+	})
+	if i == len(l.entries) {
+		if err := l.apply(entry, false); err != nil {
+			return nil, err
+		}
+		l.entries = append(l.entries, entry)
+		return nil, nil
+	}
+	//the new entry has no checkpoints, the players are restored to the checkpoints of the later results
+	return l.correct(func() (int, map[string]bool, error) {
+		l.entries = append(l.entries, nil)
+		copy(l.entries[i+1:], l.entries[i:])
+		l.entries[i] = entry
+		return i, namesOf(entry.result.players()), nil
+	})
+}
+
+//Invalidate voids the result of the id and recomputes the affected players
+func (l *Ledger) Invalidate(id string) error {
+	l.mu.Lock()
+	event, err := l.correct(func() (int, map[string]bool, error) {
+		for i, entry := range l.entries {
+			if entry.result.ID != id || entry.void {
+				continue
+			}
+			entry.void = true
+			return i, namesOf(entry.result.players()), nil
+		}
+		return -1, nil, errors.Errorf("result %s is not found", id)
+	})
+	l.mu.Unlock()
+	l.service.Config.Hooks.fireRecompute(event)
+	return err
+}
+
+//Ban voids all results of the player of the id, for example a cheater, and recomputes the opponents.
+//The banned player is restored to the state before the first voided result.
+func (l *Ledger) Ban(id string) error {
+	l.mu.Lock()
+	event, err := l.correct(func() (int, map[string]bool, error) {
+		start := -1
+		affected := make(map[string]bool)
+		for i, entry := range l.entries {
+			if entry.void || !entry.result.involves(map[string]bool{id: true}) {
+				continue
+			}
+			if start < 0 {
+				start = i
+			}
+			entry.void = true
+			for _, player := range entry.result.players() {
+				affected[player] = true
+			}
+		}
+		return start, affected, nil
+	})
+	l.mu.Unlock()
+	l.service.Config.Hooks.fireRecompute(event)
+	return err
+}
+
+//Merge merges the player of the id from into the player of the id into, such as duplicate accounts.
+//The results of from become the results of into, and into is recomputed from the earliest result of both.
+//The results between both players are voided. from is removed from the ledger.
+func (l *Ledger) Merge(from, into string) error {
+	l.mu.Lock()
+	event, err := l.correct(func() (int, map[string]bool, error) {
+		if from == into {
+			return -1, nil, errors.New("can not merge the player into itself")
+		}
+		if _, ok := l.players[into]; !ok {
+			return -1, nil, errors.Errorf("player %s is not found", into)
+		}
+		if _, ok := l.players[from]; !ok {
+			return -1, nil, errors.Errorf("player %s is not found", from)
+		}
+		both := map[string]bool{from: true, into: true}
+		start := -1
+		for i, entry := range l.entries {
+			if entry.void || !entry.result.involves(both) {
+				continue
+			}
+			if start < 0 {
+				start = i
+			}
+			names := namesOf(entry.result.players())
+			if names[from] && names[into] {
+				entry.void = true
+				continue
+			}
+			for s := range entry.result.Sides {
+				players := make([]string, len(entry.result.Sides[s].Players))
+				for j, name := range entry.result.Sides[s].Players {
+					if name == from {
+						name = into
+					}
+					players[j] = name
+				}
+				entry.result.Sides[s].Players = players
+			}
+		}
+		delete(l.players, from)
+		return start, both, nil
+	})
+	l.mu.Unlock()
+	l.service.Config.Hooks.fireRecompute(event)
+	return err
+}
+
+//ledgerBackup is the state before a correction of the ledger, to restore it when the correction fails
+type ledgerBackup struct {
+	entries []*ledgerEntry
+	players map[string]*Player
+	//states are the binary form of the players changed by the recomputation
+	states map[*Player][]byte
+	//series are the history of the players before the stale points are removed
+	series map[string]RatingSeries
+}
+
+//correct changes the entries by mutate, and recomputes the affected players from the start index it returns.
+//If mutate or the recomputation fails, the entries, the players and the history are restored.
+func (l *Ledger) correct(mutate func() (int, map[string]bool, error)) (*RecomputeEvent, error) {
+	backup := &ledgerBackup{
+		entries: make([]*ledgerEntry, len(l.entries)),
+		players: make(map[string]*Player, len(l.players)),
+		states:  make(map[*Player][]byte),
+		series:  make(map[string]RatingSeries),
+	}
+	for i, entry := range l.entries {
+		saved := *entry
+		saved.result.Sides = append([]Side(nil), entry.result.Sides...)
+		backup.entries[i] = &saved
+	}
+	for name, player := range l.players {
+		backup.players[name] = player
+	}
+	start, affected, err := mutate()
+	if err == nil && start >= 0 {
+		var event *RecomputeEvent
+		if event, err = l.recompute(start, affected, backup); err == nil {
+			return event, nil
+		}
+	}
+	if err == nil {
+		return nil, nil
+	}
+	if rerr := l.restore(backup); rerr != nil {
+		return nil, errors.Wrapf(err, "failed to restore the ledger: %v", rerr)
+	}
+	return nil, err
+}
+
+func (l *Ledger) restore(backup *ledgerBackup) error {
+	l.entries = backup.entries
+	l.players = backup.players
+	for player, state := range backup.states {
+		if err := player.UnmarshalBinary(state); err != nil {
+			return errors.Wrapf(err, "restore %s", player.Name())
+		}
+	}
+	for key, series := range backup.series {
+		if err := l.service.Config.History.Replace(key, time.Time{}, series); err != nil {
+			return errors.Wrapf(err, "restore history of %s", key)
+		}
+	}
+	return nil
+}
+
+//recompute restores the affected players and applies the valid results again from the start index.
+//A player becomes affected at the first valid result with an affected player, the transitive closure of the later results.
+//The results before that are kept, so the player is restored to the checkpoint of the first result since then.
+//The points of the replayed and the voided results are removed from the history, and the replay records them again.
+//The hooks and the instrumentation are not called for the replayed results, the hooks get a RecomputeEvent instead.
+func (l *Ledger) recompute(start int, affected map[string]bool, backup *ledgerBackup) (*RecomputeEvent, error) {
+	since := make(map[string]int, len(affected))
+	for name := range affected {
+		since[name] = start
+	}
+	replay := make([]bool, len(l.entries))
+	for i := start; i < len(l.entries); i++ {
+		entry := l.entries[i]
+		if entry.void || !entry.result.involves(affected) {
+			continue
+		}
+		replay[i] = true
+		for _, name := range entry.result.players() {
+			if !affected[name] {
+				affected[name] = true
+				since[name] = i
+			}
+		}
+	}
+	names := make([]string, 0, len(affected))
+	for name := range affected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	event := &RecomputeEvent{From: l.entries[start].at}
+	for _, name := range names {
+		player, ok := l.players[name]
+		if !ok {
+			continue
+		}
+		state, err := player.MarshalBinary()
+		if err != nil {
+			return nil, errors.Wrapf(err, "save %s", name)
+		}
+		backup.states[player] = state
+		event.Players = append(event.Players, player)
+		//the player has not changed between since and the first checkpoint
+		for _, entry := range l.entries[since[name]:] {
+			if checkpoint, ok := entry.checkpoints[name]; ok {
+				if err := player.UnmarshalBinary(checkpoint); err != nil {
+					return nil, errors.Wrapf(err, "restore %s", name)
+				}
+				break
+			}
+		}
+	}
+	if err := l.removePoints(start, replay, backup); err != nil {
+		return nil, err
+	}
+	for i := start; i < len(l.entries); i++ {
+		if !replay[i] {
+			continue
+		}
+		if err := l.apply(l.entries[i], true); err != nil {
+			return nil, errors.Wrapf(err, "recompute %s", l.entries[i].result.ID)
+		}
+	}
+	return event, nil
+}
+
+//removePoints removes the history points of the results to be replayed and the voided results since the start index
+func (l *Ledger) removePoints(start int, replay []bool, backup *ledgerBackup) error {
+	history := l.service.Config.History
+	stale := make(map[string][]HistoryPoint)
+	for i := start; i < len(l.entries); i++ {
+		entry := l.entries[i]
+		if !replay[i] && !entry.void {
+			continue
+		}
+		for _, p := range entry.points {
+			stale[p.key] = append(stale[p.key], p.point)
+		}
+		entry.points = nil
+	}
+	if history == nil {
+		return nil
+	}
+	return removeHistory(history, stale, backup.series)
+}
+
+//apply takes the checkpoints of the players and applies the result.
+//On replay, the hooks and the instrumentation are not called.
+func (l *Ledger) apply(entry *ledgerEntry, replay bool) error {
+	result := entry.result
+	if len(result.Sides) < 2 {
+		return errors.Errorf("result %s: two or more sides are required", result.ID)
+	}
+	elements := make([]Element, 0, len(result.Sides))
+	checkpoints := make(map[string][]byte)
+	for _, side := range result.Sides {
+		members := make(Players, 0, len(side.Players))
+		for _, id := range side.Players {
+			player, ok := l.players[id]
+			if !ok {
+				return errors.Errorf("result %s: player %s is not found", result.ID, id)
+			}
+			if _, ok := checkpoints[id]; ok {
+				return errors.Errorf("result %s: player %s is in the result twice", result.ID, id)
+			}
+			checkpoint, err := player.MarshalBinary()
+			if err != nil {
+				return errors.Wrapf(err, "checkpoint %s", id)
+			}
+			checkpoints[id] = checkpoint
+			members = append(members, player)
+		}
+		switch len(members) {
+		case 0:
+			return errors.Errorf("result %s: empty side", result.ID)
+		case 1:
+			elements = append(elements, members[0])
+		default:
+			elements = append(elements, l.service.NewTeam(strings.Join(side.Players, ","), members))
+		}
+	}
+	match, err := l.service.NewMatchInContext(result.Context, elements...)
+	if err != nil {
+		return errors.Wrapf(err, "result %s", result.ID)
+	}
+	match.WithID(result.ID)
+	for i, side := range result.Sides {
+		if err := match.Add(elements[i], side.Score); err != nil {
+			return err
+		}
+		if side.Advantage != 0 {
+			if err := match.SetAdvantage(elements[i], side.Advantage); err != nil {
+				return err
+			}
+		}
+	}
+	//the players are restored before the result, it is late only when the ledger does not reach back to it
+	config := *l.service.Config
+	config.LatePolicy = RejectLate
+	if entry.current {
+		config.LatePolicy = ApplyLateToCurrent
+	}
+	if replay {
+		config.Hooks = nil
+		config.Instrumentation = nil
+	} else if entry.origin != nil {
+		config.Hooks = entry.origin.hooks(config.Hooks, elements)
+	}
+	var recorder *pointRecorder
+	if config.History != nil {
+		recorder = &pointRecorder{HistoryStore: config.History}
+		config.History = recorder
+	}
+	if err := match.Apply(result.At, &config); err != nil {
+		return err
+	}
+	entry.checkpoints = checkpoints
+	entry.origin = nil
+	if recorder != nil {
+		entry.points = recorder.points
+	}
+	return nil
+}
+
+func namesOf(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
@@ -0,0 +1,271 @@
+package ratingutil_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/mashiike/rating/ratingutil"
+)
+
+var ledgerStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func ledgerResults() []ratingutil.Result {
+	result := func(id string, day int, winner, loser string) ratingutil.Result {
+		return ratingutil.Result{
+			ID: id,
+			At: ledgerStart.Add(time.Duration(day)*ratingutil.PeriodDay + time.Hour),
+			Sides: []ratingutil.Side{
+				{Players: []string{winner}, Score: 1.0},
+				{Players: []string{loser}},
+			},
+		}
+	}
+	return []ratingutil.Result{
+		result("r1", 0, "a", "b"),
+		result("r2", 0, "c", "d"),
+		result("r3", 1, "b", "c"),
+		result("r4", 2, "d", "e"),
+		result("r5", 3, "e", "a"),
+		result("r6", 3, "c", "a"),
+	}
+}
+
+func newLedger(t *testing.T, results []ratingutil.Result) *ratingutil.Ledger {
+	t.Helper()
+	return newLedgerWithConfig(t, ratingutil.NewConfig(), results)
+}
+
+func newLedgerWithConfig(t *testing.T, config *ratingutil.Config, results []ratingutil.Result) *ratingutil.Ledger {
+	t.Helper()
+	svc := ratingutil.New(config.WithRatingPeriod(ratingutil.PeriodDay))
+	ledger, err := svc.NewLedger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if err := ledger.Add(svc.NewPlayer(name, rating.Default(0.06), ledgerStart)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range results {
+		if err := ledger.Record(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ledger
+}
+
+//assertSameRatings checks the ledger against the ledger computed from scratch
+func assertSameRatings(t *testing.T, got, want *ratingutil.Ledger, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		p, _ := got.Player(name)
+		q, _ := want.Player(name)
+		if p.Rating().Format(rating.DefaultFormat) != q.Rating().Format(rating.DefaultFormat) {
+			t.Errorf("%s = %v, want %v", name, p.Rating(), q.Rating())
+		}
+	}
+}
+
+func without(results []ratingutil.Result, ids ...string) []ratingutil.Result {
+	var kept []ratingutil.Result
+	for _, r := range results {
+		keep := true
+		for _, id := range ids {
+			if r.ID == id {
+				keep = false
+			}
+		}
+		if keep {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func TestLedgerInvalidate(t *testing.T) {
+	ledger := newLedger(t, ledgerResults())
+	d, _ := ledger.Player("d")
+	before := d.Rating()
+	if err := ledger.Invalidate("r1"); err != nil {
+		t.Fatal(err)
+	}
+	assertSameRatings(t, ledger, newLedger(t, without(ledgerResults(), "r1")), "a", "b", "c", "d", "e")
+	if d.Rating() != before {
+		t.Errorf("unaffected player is recomputed: %v -> %v", before, d.Rating())
+	}
+	if len(ledger.Results()) != 5 {
+		t.Errorf("results = %d", len(ledger.Results()))
+	}
+	if err := ledger.Invalidate("r1"); err == nil {
+		t.Error("invalidate twice should fail")
+	}
+}
+
+func TestLedgerBan(t *testing.T) {
+	ledger := newLedger(t, ledgerResults())
+	if err := ledger.Ban("c"); err != nil {
+		t.Fatal(err)
+	}
+	assertSameRatings(t, ledger, newLedger(t, without(ledgerResults(), "r2", "r3", "r6")), "a", "b", "c", "d", "e")
+}
+
+func TestLedgerMerge(t *testing.T) {
+	ledger := newLedger(t, ledgerResults())
+	if err := ledger.Merge("e", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ledger.Player("e"); ok {
+		t.Error("merged player is in the ledger")
+	}
+	merged := ledgerResults()
+	merged[3].Sides[1].Players = []string{"b"}
+	merged[4].Sides[0].Players = []string{"b"}
+	assertSameRatings(t, ledger, newLedger(t, merged), "a", "b", "c", "d")
+}
+
+//failingHistory fails to record when fail is set
+type failingHistory struct {
+	*ratingutil.MemoryHistory
+	fail bool
+}
+
+func (h *failingHistory) Record(key string, point ratingutil.HistoryPoint) error {
+	if h.fail {
+		return errors.New("history is down")
+	}
+	return h.MemoryHistory.Record(key, point)
+}
+
+func TestLedgerRecomputeHistory(t *testing.T) {
+	history := &failingHistory{MemoryHistory: ratingutil.NewMemoryHistory()}
+	hooks := ratingutil.NewHooks()
+	applied := 0
+	var recomputed []*ratingutil.RecomputeEvent
+	hooks.OnAfterApply(func(*ratingutil.ApplyEvent) { applied++ })
+	hooks.OnRecompute(func(e *ratingutil.RecomputeEvent) { recomputed = append(recomputed, e) })
+	ledger := newLedgerWithConfig(t, ratingutil.NewConfig().WithHistory(history).WithHooks(hooks), ledgerResults())
+	names := []string{"a", "b", "c", "d", "e"}
+	series := func(h ratingutil.HistoryStore) map[string]ratingutil.RatingSeries {
+		ret := make(map[string]ratingutil.RatingSeries)
+		for _, name := range names {
+			ret[name], _ = h.Series(name)
+		}
+		return ret
+	}
+
+	//a failed recomputation leaves the ledger, the players and the history as they were
+	before := series(history)
+	ratings := make(map[string]rating.Rating)
+	for _, name := range names {
+		p, _ := ledger.Player(name)
+		ratings[name] = p.Rating()
+	}
+	history.fail = true
+	if err := ledger.Invalidate("r1"); err == nil {
+		t.Fatal("invalidate with the failing history should fail")
+	}
+	history.fail = false
+	if !reflect.DeepEqual(series(history), before) {
+		t.Error("history is changed by the failed invalidate")
+	}
+	for _, name := range names {
+		if p, _ := ledger.Player(name); p.Rating() != ratings[name] {
+			t.Errorf("%s is changed by the failed invalidate: %v", name, p.Rating())
+		}
+	}
+	if len(ledger.Results()) != 6 {
+		t.Errorf("results = %d", len(ledger.Results()))
+	}
+
+	applied = 0
+	if err := ledger.Invalidate("r1"); err != nil {
+		t.Fatal(err)
+	}
+	if applied != 0 || len(recomputed) != 1 {
+		t.Errorf("hooks are called for each replayed result: applied %d, recomputed %d", applied, len(recomputed))
+	}
+	if got := recomputed[0].From; !got.Equal(ledgerResults()[0].At) {
+		t.Errorf("recomputed from %v", got)
+	}
+	//the history is the same as the history without the voided result
+	fresh := ratingutil.NewMemoryHistory()
+	newLedgerWithConfig(t, ratingutil.NewConfig().WithHistory(fresh), without(ledgerResults(), "r1"))
+	if got, want := series(history), series(fresh); !reflect.DeepEqual(got, want) {
+		for _, name := range names {
+			if !reflect.DeepEqual(got[name], want[name]) {
+				t.Errorf("history of %s = %v, want %v", name, got[name], want[name])
+			}
+		}
+	}
+}
+
+func TestLedgerPlayerID(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig())
+	svc.RetainResults()
+	first := svc.NewDefaultPlayer("bob").WithID("1")
+	second := svc.NewDefaultPlayer("bob").WithID("2")
+	match, _ := svc.NewMatch(first, second)
+	match.Add(first, 1.0)
+	if err := svc.Apply(match); err != nil {
+		t.Fatal(err)
+	}
+	if first.Rating().Strength() <= second.Rating().Strength() {
+		t.Errorf("both players should be updated: %v %v", first.Rating(), second.Rating())
+	}
+	ledger := svc.RetainResults()
+	for _, player := range []*ratingutil.Player{first, second} {
+		if got, ok := ledger.Player(player.ID()); !ok || got != player {
+			t.Errorf("player %s is not found by the id", player.ID())
+		}
+	}
+
+	//the players of the same id are rejected
+	alice := svc.NewDefaultPlayer("alice")
+	other := svc.NewDefaultPlayer("alice")
+	match, _ = svc.NewMatch(alice, other)
+	match.Add(alice, 1.0)
+	if err := svc.Apply(match); err == nil {
+		t.Error("players of the same id should be rejected")
+	}
+	if alice.Rating() != other.Rating() || match.Scores()[alice] != 1.0 {
+		t.Errorf("rejected match is applied: %v %v", alice.Rating(), other.Rating())
+	}
+	impostor := svc.NewDefaultPlayer("carol").WithID("1")
+	match, _ = svc.NewMatch(impostor, alice)
+	match.Add(impostor, 1.0)
+	if err := svc.Apply(match); err == nil {
+		t.Error("another player of the id in the ledger should be rejected")
+	}
+	if err := ledger.Add(impostor); err == nil {
+		t.Error("adding another player of the id should fail")
+	}
+}
+
+func TestLedgerRecomputeContext(t *testing.T) {
+	inContext := func(results []ratingutil.Result) []ratingutil.Result {
+		ret := make([]ratingutil.Result, len(results))
+		for i, r := range results {
+			r.Context = "ranked"
+			ret[i] = r
+		}
+		return ret
+	}
+	config := ratingutil.NewConfig()
+	ledger := newLedgerWithConfig(t, config, inContext(ledgerResults()))
+	b, _ := ledger.Player("b")
+	ranked := b.Context("ranked", config)
+	if err := ledger.Invalidate("r1"); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := newLedger(t, inContext(without(ledgerResults(), "r1"))).Player("b")
+	if b.Context("ranked", config) != ranked {
+		t.Error("the held player of the context is replaced")
+	}
+	if got, expected := ranked.Rating(), want.Ratings()["ranked"]; got.Format(rating.DefaultFormat) != expected.Format(rating.DefaultFormat) {
+		t.Errorf("held context = %v, want %v", got, expected)
+	}
+}
@@ -3,6 +3,7 @@ package ratingutil
 import (
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/mashiike/rating"
@@ -15,12 +16,38 @@ type ApplyStrategy func(map[Element]rating.Rating, map[Element]float64) error
 
 //Match is a model that represents multiple Team / Player battles
 type Match struct {
+	//applyMu serializes Apply, mu guards the scores and is not held while applying,
+	//so that the hooks can read the match.
+	applyMu       sync.Mutex
+	mu            sync.Mutex
+	id            string
 	scores        map[Element]float64
+	advantages    map[Element]float64
 	applyStrategy ApplyStrategy
+	context       string
+	config        *Config
+}
+
+//Context returns the key of the player context in which the match is played, the default context is empty.
+func (m *Match) Context() string {
+	return m.context
+}
+
+//ID returns the match identifier
+func (m *Match) ID() string {
+	return m.id
+}
+
+//WithID is set the match identifier, it is recorded in the rating history
+func (m *Match) WithID(id string) *Match {
+	m.id = id
+	return m
 }
 
 //Add function adds a Score.
 func (m *Match) Add(element Element, score float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if _, ok := m.scores[element]; !ok {
 		return errors.New("this element not join match")
 	}
@@ -28,15 +55,67 @@ func (m *Match) Add(element Element, score float64) error {
 	return nil
 }
 
+//SetAdvantage sets the advantage of the element on the configured Scale, such as home advantage or handicap.
+//The expected score is calculated as if the strength were higher by advantage, in Apply, Preview and WinProbs.
+func (m *Match) SetAdvantage(element Element, advantage float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.scores[element]; !ok {
+		return errors.New("this element not join match")
+	}
+	if m.advantages == nil {
+		m.advantages = make(map[Element]float64, len(m.scores))
+	}
+	m.advantages[element] = advantage
+	return nil
+}
+
+//SetAdvantaged gives the element the fixed advantage of the match context, set by Config.WithAdvantage.
+func (m *Match) SetAdvantaged(element Element) error {
+	return m.SetAdvantage(element, m.config.Advantages[m.context])
+}
+
 //Reset returns to the zero score
 func (m *Match) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reset()
+}
+
+func (m *Match) reset() {
 	for element := range m.scores {
 		m.scores[element] = 0.0
 	}
 }
 
+//snapshot returns the copies of the scores and the advantages to be applied
+func (m *Match) snapshot() (map[Element]float64, map[Element]float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	scores := make(map[Element]float64, len(m.scores))
+	for element, score := range m.scores {
+		scores[element] = score
+	}
+	advantages := make(map[Element]float64, len(m.advantages))
+	for element, advantage := range m.advantages {
+		advantages[element] = advantage
+	}
+	return scores, advantages
+}
+
+//consume subtracts the applied scores, the scores added while applying are kept
+func (m *Match) consume(scores map[Element]float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for element, score := range scores {
+		m.scores[element] -= score
+	}
+}
+
 //Scores return copy internal scores
 func (m *Match) Scores() map[Element]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	ret := make(map[Element]float64, len(m.scores))
 	for elem, score := range m.scores {
 		ret[elem] = score
@@ -46,38 +125,271 @@ func (m *Match) Scores() map[Element]float64 {
 
 //Ratings return match joined Team/Players current Rating
 func (m *Match) Ratings() map[Element]rating.Rating {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ratings()
+}
+
+func (m *Match) ratings() map[Element]rating.Rating {
 	ratings := make(map[Element]rating.Rating, len(m.scores))
 	for target := range m.scores {
-		ratings[target] = target.Rating()
+		ratings[target] = inContext(target, m.context, m.config, nil).Rating()
 	}
 	return ratings
 }
 
+//ApplyError is an error of reflecting the result between two Team / Player
+type ApplyError struct {
+	Target   Element
+	Opponent Element
+	Err      error
+}
+
+//Error returns the string representation of a ApplyError.
+func (e *ApplyError) Error() string {
+	return fmt.Sprintf("failed apply %v vs %v: %v", e.Target.Name(), e.Opponent.Name(), e.Err)
+}
+
+//Cause returns the underlying error, for github.com/pkg/errors
+func (e *ApplyError) Cause() error {
+	return e.Err
+}
+
+//Unwrap returns the underlying error, for errors.Is / errors.As
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
 //Apply function determines the current score and reflects it on Team / Player's Rating.
+//Apply is atomic. If it fails on the way, all Team / Player states are restored and the scores are kept.
+//Apply is safe for concurrent use, the joined players are locked while applying.
+//The scores and the advantages are taken at the start, the hooks can read the match.
 func (m *Match) Apply(scoresAt time.Time, config *Config) error {
-	scores := make(map[Element]float64, len(m.scores))
-	for target, score := range m.scores {
-		if err := target.Prepare(scoresAt, config); err != nil {
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+	var start time.Time
+	if config.Instrumentation != nil {
+		start = time.Now()
+	}
+	scores, advantages := m.snapshot()
+	tx, event, err := m.applyLocked(scoresAt, config, scores, advantages)
+	if err == nil {
+		m.consume(scores)
+	}
+	if config.Instrumentation != nil {
+		config.Instrumentation.MatchApplied(m.context, time.Since(start), err)
+	}
+	if tx != nil {
+		tx.fire()
+	}
+	if event != nil {
+		config.Hooks.fireAfterApply(event)
+	}
+	return err
+}
+
+//applyLocked applies the match with the players locked, and returns the committed transaction and the event for the hooks
+func (m *Match) applyLocked(scoresAt time.Time, config *Config, scores, advantages map[Element]float64) (*transaction, *ApplyEvent, error) {
+	targets := make([]Element, 0, len(scores))
+	for target := range scores {
+		targets = append(targets, target)
+	}
+	unlock := lockPlayers(uniquePlayers(targets))
+	defer unlock()
+	//resolve the context after locking, because a new context is seeded from the player
+	//the contexts created here are unregistered if the match is not applied
+	resolved := make(map[Element]Element, len(targets))
+	elements := make([]Element, 0, len(targets))
+	var created Players
+	for _, target := range targets {
+		resolved[target] = inContext(target, m.context, config, &created)
+		elements = append(elements, resolved[target])
+	}
+	if err := config.checkLate(uniquePlayers(elements), scoresAt); err != nil {
+		unregisterContexts(created)
+		return nil, nil, err
+	}
+	var event *ApplyEvent
+	if config.Hooks.hasApply() {
+		event = &ApplyEvent{
+			Match:   m,
+			At:      scoresAt,
+			Context: m.context,
+			Scores:  make(map[Element]float64, len(scores)),
+			Before:  make(map[Element]rating.Rating, len(scores)),
+		}
+		for target, score := range scores {
+			event.Scores[target] = score
+			event.Before[target] = resolved[target].Rating()
+		}
+		if err := config.Hooks.fireBeforeApply(event); err != nil {
+			unregisterContexts(created)
+			return nil, nil, errors.Wrap(err, "vetoed by hook")
+		}
+	}
+	players := uniquePlayers(elements)
+	var before []float64
+	if config.Instrumentation != nil {
+		before = config.strengths(players)
+	}
+	tx := begin(players, config)
+	tx.created = created
+	if err := m.apply(scoresAt, tx.config, resolved, scores, advantages); err != nil {
+		tx.rollback()
+		return nil, nil, err
+	}
+	//the ratings are restored if the history is not recorded, so that a retry does not apply the match twice
+	if err := tx.commit(); err != nil {
+		tx.rollback()
+		return nil, nil, err
+	}
+	if config.Instrumentation != nil {
+		for i, after := range config.strengths(players) {
+			config.Instrumentation.RatingChanged(m.context, after-before[i])
+		}
+	}
+	if event != nil {
+		event.After = make(map[Element]rating.Rating, len(resolved))
+		for target, element := range resolved {
+			event.After[target] = element.Rating()
+		}
+	}
+	return tx, event, nil
+}
+
+func (m *Match) apply(scoresAt time.Time, config *Config, resolved map[Element]Element, scores, advantages map[Element]float64) error {
+	for target := range scores {
+		if err := resolved[target].Prepare(scoresAt, config); err != nil {
 			return errors.Wrapf(err, "failed prepare %v", target.Name())
 		}
-		scores[target] = score
 	}
-	ratings := m.Ratings()
-	m.Reset()
-	return m.applyStrategy(ratings, scores)
+	originals := make(map[Element]Element, len(scores))
+	elemScores := make(map[Element]float64, len(scores))
+	ratings := make(map[Element]rating.Rating, len(scores))
+	for target, score := range scores {
+		elem := withAdvantage(resolved[target], config.Scale.Phi(advantages[target]))
+		originals[elem] = target
+		elemScores[elem] = score
+		ratings[elem] = config.Scale.Shift(resolved[target].Rating(), advantages[target])
+	}
+	defer clearAdvantages(resolved)
+	if err := m.applyStrategy(ratings, elemScores); err != nil {
+		var applyErr *ApplyError
+		if errors.As(err, &applyErr) {
+			if original, ok := originals[applyErr.Target]; ok {
+				applyErr.Target = original
+			}
+			if original, ok := originals[applyErr.Opponent]; ok {
+				applyErr.Opponent = original
+			}
+		}
+		return err
+	}
+	elements := make([]Element, 0, len(resolved))
+	for _, element := range resolved {
+		elements = append(elements, element)
+	}
+	for _, player := range uniquePlayers(elements) {
+		if err := player.played(scoresAt, config); err != nil {
+			return err
+		}
+	}
+	for target := range scores {
+		for _, player := range playersOf(resolved[target]) {
+			if err := player.record(config, HistoryPoint{
+				At:      scoresAt,
+				Kind:    MatchApplied,
+				MatchID: m.id,
+				Rating:  player.Rating(),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//uniquePlayers returns the players of the elements without duplication
+func uniquePlayers(elements []Element) Players {
+	seen := make(map[*Player]bool)
+	players := make(Players, 0, len(elements))
+	for _, element := range elements {
+		for _, player := range playersOf(element) {
+			if seen[player] {
+				continue
+			}
+			seen[player] = true
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+//Preview returns the projected Rating of each Team / Player when it wins, draws or loses against all the others.
+//The projection includes the end of the rating period with the configured Tau.
+//Team / Player estimated state is not changed.
+func (m *Match) Preview(config *Config) (map[Element]rating.Preview, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ratings := m.ratings()
+	previews := make(map[Element]rating.Preview, len(ratings))
+	for target := range ratings {
+		opponents := make([]rating.Rating, 0, len(ratings)-1)
+		for opponent, r := range ratings {
+			if target == opponent {
+				continue
+			}
+			opponents = append(opponents, config.Scale.Shift(r, m.advantages[opponent]-m.advantages[target]))
+		}
+		p, err := previewElement(inContext(target, m.context, config, nil), opponents, config.Tau)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed preview %v", target.Name())
+		}
+		previews[target] = p
+	}
+	return previews, nil
+}
+
+func previewElement(element Element, opponents []rating.Rating, tau float64) (rating.Preview, error) {
+	players := playersOf(element)
+	if players == nil {
+		return rating.NewEstimated(element.Rating()).PreviewMatches(opponents, tau)
+	}
+	current := make([]rating.Rating, 0, len(players))
+	win := make([]rating.Rating, 0, len(players))
+	draw := make([]rating.Rating, 0, len(players))
+	lose := make([]rating.Rating, 0, len(players))
+	for _, player := range players {
+		p, err := player.estimated.PreviewMatches(opponents, tau)
+		if err != nil {
+			return p, errors.Wrapf(err, "preview %v", player)
+		}
+		current = append(current, p.Current)
+		win = append(win, p.Win)
+		draw = append(draw, p.Draw)
+		lose = append(lose, p.Lose)
+	}
+	return rating.Preview{
+		Current: rating.Average(current),
+		Win:     rating.Average(win),
+		Draw:    rating.Average(draw),
+		Lose:    rating.Average(lose),
+	}, nil
 }
 
 //WinProbs returns the probability that each Team / Player will be first
 func (m *Match) WinProbs() map[Element]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	probs := make(map[Element]float64, len(m.scores))
-	ratings := m.Ratings()
+	ratings := m.ratings()
 	for target := range m.scores {
 		probs[target] = 1.0
 		for opponent, r := range ratings {
 			if target == opponent {
 				continue
 			}
-			probs[target] *= ratings[target].WinProb(r)
+			probs[target] *= m.config.Scale.Shift(ratings[target], m.advantages[target]).WinProb(m.config.Scale.Shift(r, m.advantages[opponent]))
 		}
 	}
 	return probs
@@ -97,6 +409,43 @@ func (m *Match) String() string {
 	return str + "]"
 }
 
+//withAdvantage reflects the advantage in the expected score of the element.
+//The rating of the opponent passed to ApplyMatch contains the opponent advantage,
+//so subtracting own advantage from it makes the difference of both advantages.
+//The players carry own advantage while they are locked, so the strategy receives the elements as they are.
+//Only an element other than Team / Player is wrapped.
+func withAdvantage(element Element, advantage float64) Element {
+	if advantage == 0.0 {
+		return element
+	}
+	players := playersOf(element)
+	if players == nil {
+		return &advantagedElement{Element: element, advantage: advantage}
+	}
+	for _, player := range players {
+		player.advantage = advantage
+	}
+	return element
+}
+
+func clearAdvantages(resolved map[Element]Element) {
+	for _, element := range resolved {
+		for _, player := range playersOf(element) {
+			player.advantage = 0.0
+		}
+	}
+}
+
+//advantagedElement carries the advantage on the Glicko-2 scale of an element other than Team / Player
+type advantagedElement struct {
+	Element
+	advantage float64
+}
+
+func (e *advantagedElement) ApplyMatch(opponent rating.Rating, score float64) error {
+	return e.Element.ApplyMatch(rating.NewGlicko2(opponent.Mu()-e.advantage, opponent.Phi(), opponent.Sigma()), score)
+}
+
 //AsRoundrobin considers Multiplayer Matches to be a round-trip tournament ApplyStrategy
 func AsRoundrobin(ratings map[Element]rating.Rating, scores map[Element]float64) error {
 	for target, score1 := range scores {
@@ -113,7 +462,7 @@ func AsRoundrobin(ratings map[Element]rating.Rating, scores map[Element]float64)
 				score = rating.ScoreDraw
 			}
 			if err := target.ApplyMatch(ratings[opponent], score); err != nil {
-				return errors.Wrapf(err, "failed apply %v vs %v", target.Name(), opponent.Name())
+				return &ApplyError{Target: target, Opponent: opponent, Err: err}
 			}
 		}
 	}
@@ -0,0 +1,174 @@
+package ratingutil_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mashiike/rating"
+	"github.com/mashiike/rating/ratingutil"
+)
+
+func TestMatchPreview(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig())
+	sheep := svc.NewPlayer("sheep", rating.New(1700.0, 50.0, svc.Config.InitialVolatility()), svc.Config.Now())
+	goat := svc.NewDefaultPlayer("goat")
+	team := svc.NewTeam("bovidae", ratingutil.Players{sheep, goat})
+	donkey := svc.NewDefaultPlayer("donkey")
+	match, _ := svc.NewMatch(team, donkey)
+	match.Add(team, 1.0)
+
+	before := match.Ratings()
+	previews, err := svc.Preview(match)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after := match.Ratings(); after[team] != before[team] || after[donkey] != before[donkey] {
+		t.Error("preview changed the estimated state")
+	}
+	if got := match.Scores()[team]; got != 1.0 {
+		t.Errorf("preview changed the match scores: %v", got)
+	}
+	for _, elem := range []ratingutil.Element{team, donkey} {
+		win, _, lose := previews[elem].Deltas()
+		if win <= 0.0 || lose >= 0.0 {
+			t.Errorf("%s unexpected deltas: win=%v lose=%v", elem.Name(), win, lose)
+		}
+	}
+
+}
+
+func TestMatchApplyRollback(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig().WithHistory(ratingutil.NewMemoryHistory()))
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	svc.Config.WithApplyStrategy(func(ratings map[ratingutil.Element]rating.Rating, scores map[ratingutil.Element]float64) error {
+		if err := ratingutil.AsRoundrobin(ratings, scores); err != nil {
+			return err
+		}
+		//invalid score, fails after all players are changed
+		return &ratingutil.ApplyError{
+			Target:   sheep,
+			Opponent: goat,
+			Err:      sheep.ApplyMatch(ratings[goat], 2.0),
+		}
+	})
+	match, _ := svc.NewMatch(sheep, goat)
+	match.Add(sheep, 1.0)
+
+	before := match.Ratings()
+	err := svc.Apply(match)
+	var applyErr *ratingutil.ApplyError
+	if !errors.As(err, &applyErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applyErr.Target != sheep || applyErr.Opponent != goat {
+		t.Errorf("unexpected failed pair: %v", applyErr)
+	}
+	if after := match.Ratings(); after[sheep] != before[sheep] || after[goat] != before[goat] {
+		t.Errorf("ratings are not restored: %v", after)
+	}
+	if got := match.Scores()[sheep]; got != 1.0 {
+		t.Errorf("scores are not kept: %v", got)
+	}
+	if _, err := svc.History("sheep"); err == nil {
+		t.Error("history is recorded by failed apply")
+	}
+}
+
+func TestMatchAdvantage(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig())
+	home := svc.NewDefaultPlayer("home")
+	away := svc.NewDefaultPlayer("away")
+	match, _ := svc.NewMatch(home, away)
+	if err := match.SetAdvantage(home, 100.0); err != nil {
+		t.Fatal(err)
+	}
+	if err := match.SetAdvantage(svc.NewDefaultPlayer("other"), 100.0); err == nil {
+		t.Error("element not joined should be error")
+	}
+	probs := match.WinProbs()
+	if probs[home] <= 0.5 || probs[away] >= 0.5 {
+		t.Errorf("unexpected win probs: %v", probs)
+	}
+
+	plain, _ := svc.NewMatch(svc.NewDefaultPlayer("home2"), svc.NewDefaultPlayer("away2"))
+	previews, err := svc.Preview(match)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainPreviews, err := svc.Preview(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for elem, p := range plainPreviews {
+		if elem.Name() != "home2" {
+			continue
+		}
+		win, _, _ := previews[home].Deltas()
+		plainWin, _, _ := p.Deltas()
+		if win >= plainWin {
+			t.Errorf("advantaged win should gain less: %v, without %v", win, plainWin)
+		}
+	}
+
+	homeBefore, awayBefore := home.Rating(), away.Rating()
+	match.Add(home, 1.0)
+	if err := svc.Apply(match); err != nil {
+		t.Fatal(err)
+	}
+	//the same as the match against the opponent weaker by the advantage
+	expected := rating.NewEstimated(homeBefore)
+	if err := expected.ApplyMatch(awayBefore.Shift(-100.0), rating.ScoreWin); err != nil {
+		t.Fatal(err)
+	}
+	if got := home.Rating(); got != expected.Rating() {
+		t.Errorf("home got %v, expected %v", got, expected.Rating())
+	}
+	expected = rating.NewEstimated(awayBefore)
+	if err := expected.ApplyMatch(homeBefore.Shift(100.0), rating.ScoreLose); err != nil {
+		t.Fatal(err)
+	}
+	if got := away.Rating(); got != expected.Rating() {
+		t.Errorf("away got %v, expected %v", got, expected.Rating())
+	}
+}
+
+func TestMatchAdvantageOnScale(t *testing.T) {
+	scale := rating.NewScale(25.0, 25.0/3.0)
+	svc := ratingutil.New(ratingutil.NewConfig().WithScale(scale).WithAdvantage("home", 2.0))
+	svc.Config.DefaultApplyStrategy = func(ratings map[ratingutil.Element]rating.Rating, scores map[ratingutil.Element]float64) error {
+		for elem := range ratings {
+			if _, ok := elem.(*ratingutil.Player); !ok {
+				t.Errorf("strategy receives a wrapped element: %T", elem)
+			}
+		}
+		return ratingutil.AsRoundrobin(ratings, scores)
+	}
+	home := svc.NewDefaultPlayer("home")
+	away := svc.NewDefaultPlayer("away")
+	match, _ := svc.NewMatchInContext("home", home, away)
+	if err := match.SetAdvantaged(home); err != nil {
+		t.Fatal(err)
+	}
+	homeBefore, awayBefore := match.Ratings()[home], match.Ratings()[away]
+	if got, expected := match.WinProbs()[home], scale.Shift(homeBefore, 2.0).WinProb(awayBefore); got != expected {
+		t.Errorf("win prob got %v, expected %v", got, expected)
+	}
+	match.Add(home, 1.0)
+	if err := svc.Apply(match); err != nil {
+		t.Fatal(err)
+	}
+	expected := rating.NewEstimated(homeBefore)
+	if err := expected.ApplyMatch(scale.Shift(awayBefore, -2.0), rating.ScoreWin); err != nil {
+		t.Fatal(err)
+	}
+	if got := home.Context("home", svc.Config).Rating(); got != expected.Rating() {
+		t.Errorf("home got %v, expected %v", got, expected.Rating())
+	}
+	//the advantage does not remain after the match
+	plain, _ := svc.NewMatchInContext("home", home, away)
+	ratings := plain.Ratings()
+	if got, expected := plain.WinProbs()[home], ratings[home].WinProb(ratings[away]); got != expected {
+		t.Errorf("win prob without advantage got %v, expected %v", got, expected)
+	}
+}
@@ -0,0 +1,176 @@
+package ratingutil
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+//Attributes are external signals of a player, such as rank in another title, self-reported skill level or placement test score.
+type Attributes map[string]float64
+
+//PriorProvider maps the attributes of a new player to the initial strength and deviation on the configured Scale.
+//The deviation is the confidence of the prior, Service keeps it between Config.MinPriorDeviationRatio of the initial deviation and the initial deviation.
+type PriorProvider interface {
+	Prior(attributes Attributes) (strength, deviation float64, err error)
+}
+
+//PriorFunc is an adapter to use a function as PriorProvider
+type PriorFunc func(attributes Attributes) (strength, deviation float64, err error)
+
+//Prior implements PriorProvider
+func (f PriorFunc) Prior(attributes Attributes) (float64, float64, error) {
+	return f(attributes)
+}
+
+//WithPrior is set Prior to config
+func (c *Config) WithPrior(prior PriorProvider) *Config {
+	c.Prior = prior
+	return c
+}
+
+//WithMinPriorDeviationRatio is set MinPriorDeviationRatio to config
+func (c *Config) WithMinPriorDeviationRatio(ratio float64) *Config {
+	c.MinPriorDeviationRatio = ratio
+	return c
+}
+
+//priorDeviation keeps the deviation of a prior wide enough, the prior is only a guess from other signals
+func (c *Config) priorDeviation(deviation float64) float64 {
+	initial := c.Scale.InitialDeviation()
+	if min := initial * c.MinPriorDeviationRatio; deviation < min || math.IsNaN(deviation) {
+		deviation = min
+	}
+	if deviation > initial {
+		deviation = initial
+	}
+	return deviation
+}
+
+//NewPlayerWithAttributes creates a player with the initial rating given by Config.Prior from the attributes.
+//If Prior is nil, it is the default player.
+func (s *Service) NewPlayerWithAttributes(name string, attributes Attributes) (*Player, error) {
+	if s.Config.Prior == nil {
+		return s.NewDefaultPlayer(name), nil
+	}
+	strength, deviation, err := s.Config.Prior.Prior(attributes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "prior of %s", name)
+	}
+	if math.IsNaN(strength) || math.IsInf(strength, 0) {
+		return nil, errors.Errorf("prior of %s: strength is not finite", name)
+	}
+	return s.NewPlayerOnScale(name, strength, s.Config.priorDeviation(deviation), s.Config.Now()), nil
+}
+
+//PriorSample is a player with the attributes at the start and the established strength, used for the calibration
+type PriorSample struct {
+	Attributes Attributes
+	Strength   float64
+}
+
+//LinearPrior is a PriorProvider of a linear model of the attributes.
+//The deviation is the standard deviation of the residuals of the calibration.
+type LinearPrior struct {
+	Intercept    float64
+	Coefficients map[string]float64
+	Deviation    float64
+}
+
+//Prior implements PriorProvider. All attributes of the model are required.
+func (p *LinearPrior) Prior(attributes Attributes) (float64, float64, error) {
+	strength := p.Intercept
+	for name, coefficient := range p.Coefficients {
+		value, ok := attributes[name]
+		if !ok {
+			return 0, 0, errors.Errorf("attribute %s is missing", name)
+		}
+		strength += coefficient * value
+	}
+	return strength, p.Deviation, nil
+}
+
+//CalibratePrior fits a LinearPrior of the named attributes to the established strengths by the linear least squares.
+//It needs more samples than the attributes.
+func CalibratePrior(samples []PriorSample, names ...string) (*LinearPrior, error) {
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+	k := len(names) + 1
+	if len(samples) <= k {
+		return nil, errors.Errorf("%d samples are not enough for %d attributes", len(samples), len(names))
+	}
+	//the normal equations (X^T X) beta = X^T y, the first column of X is the intercept
+	ata := make([][]float64, k)
+	for i := range ata {
+		ata[i] = make([]float64, k+1)
+	}
+	row := make([]float64, k)
+	for i, sample := range samples {
+		row[0] = 1.0
+		for j, name := range names {
+			value, ok := sample.Attributes[name]
+			if !ok {
+				return nil, errors.Errorf("sample %d: attribute %s is missing", i, name)
+			}
+			row[j+1] = value
+		}
+		for a := 0; a < k; a++ {
+			for b := 0; b < k; b++ {
+				ata[a][b] += row[a] * row[b]
+			}
+			ata[a][k] += row[a] * sample.Strength
+		}
+	}
+	beta, err := solveLinear(ata)
+	if err != nil {
+		return nil, err
+	}
+
+	prior := &LinearPrior{
+		Intercept:    beta[0],
+		Coefficients: make(map[string]float64, len(names)),
+	}
+	for j, name := range names {
+		prior.Coefficients[name] = beta[j+1]
+	}
+	var sse float64
+	for _, sample := range samples {
+		predicted, _, _ := prior.Prior(sample.Attributes)
+		sse += math.Pow(sample.Strength-predicted, 2)
+	}
+	prior.Deviation = math.Sqrt(sse / float64(len(samples)-k))
+	return prior, nil
+}
+
+//solveLinear solves the augmented matrix by the Gaussian elimination with partial pivoting
+func solveLinear(m [][]float64) ([]float64, error) {
+	n := len(m)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return nil, errors.New("attributes are linearly dependent")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for r := col + 1; r < n; r++ {
+			f := m[r][col] / m[col][col]
+			for c := col; c <= n; c++ {
+				m[r][c] -= f * m[col][c]
+			}
+		}
+	}
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		sum := m[r][n]
+		for c := r + 1; c < n; c++ {
+			sum -= m[r][c] * x[c]
+		}
+		x[r] = sum / m[r][r]
+	}
+	return x, nil
+}
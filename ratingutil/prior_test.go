@@ -0,0 +1,57 @@
+package ratingutil_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mashiike/rating/ratingutil"
+)
+
+func TestCalibratePrior(t *testing.T) {
+	//strength = 1000 + 50 * level - 2 * rank, with noise
+	noise := []float64{30, -25, 10, -40, 35, -5, 20, -30, 15, -10}
+	var samples []ratingutil.PriorSample
+	for i, n := range noise {
+		level, rank := float64(i%5+1), float64(i*7%11)
+		samples = append(samples, ratingutil.PriorSample{
+			Attributes: ratingutil.Attributes{"level": level, "rank": rank},
+			Strength:   1000.0 + 50.0*level - 2.0*rank + n,
+		})
+	}
+	prior, err := ratingutil.CalibratePrior(samples, "level", "rank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(prior.Coefficients["level"]-50.0) > 10.0 || math.Abs(prior.Coefficients["rank"]+2.0) > 5.0 {
+		t.Errorf("coefficients = %v, intercept = %v", prior.Coefficients, prior.Intercept)
+	}
+	if prior.Deviation < 10.0 || prior.Deviation > 50.0 {
+		t.Errorf("deviation = %v", prior.Deviation)
+	}
+	if _, err := ratingutil.CalibratePrior(samples[:3], "level", "rank"); err == nil {
+		t.Error("calibration with too few samples should fail")
+	}
+
+	svc := ratingutil.New(ratingutil.NewConfig().WithPrior(prior))
+	p, err := svc.NewPlayerWithAttributes("veteran", ratingutil.Attributes{"level": 5, "rank": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Rating().Strength(); math.Abs(got-1250.0) > 30.0 {
+		t.Errorf("strength = %v, want about 1250", got)
+	}
+	//the residual deviation is narrow, but the prior is kept wide
+	if got := p.Rating().Deviation(); got < 175.0 {
+		t.Errorf("deviation = %v, want 175 or more", got)
+	}
+	if _, err := svc.NewPlayerWithAttributes("unknown", ratingutil.Attributes{"level": 5}); err == nil {
+		t.Error("missing attribute should fail")
+	}
+	p, err = ratingutil.New(ratingutil.NewConfig()).NewPlayerWithAttributes("default", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Rating().Strength() != 1500.0 {
+		t.Errorf("without prior strength = %v", p.Rating().Strength())
+	}
+}
@@ -0,0 +1,162 @@
+package ratingutil
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mashiike/rating"
+)
+
+//PrometheusMetrics is an Instrumentation that exposes the metrics in the Prometheus text format.
+//It implements http.Handler, serve it at the scraped path such as /metrics.
+//
+//  metrics := ratingutil.NewPrometheusMetrics()
+//  svc := ratingutil.New(ratingutil.NewConfig().WithInstrumentation(metrics))
+//  http.Handle("/metrics", metrics)
+type PrometheusMetrics struct {
+	mu             sync.Mutex
+	matches        map[matchKey]uint64
+	fixErrors      uint64
+	unconverged    uint64
+	applyDuration  *histogram
+	fixDuration    *histogram
+	fixIterations  *histogram
+	strengthDeltas *histogram
+}
+
+type matchKey struct {
+	context string
+	result  string
+}
+
+//NewPrometheusMetrics creates the metrics with the default buckets
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		matches:        make(map[matchKey]uint64),
+		applyDuration:  newHistogram(0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1),
+		fixDuration:    newHistogram(0.000001, 0.000005, 0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.01),
+		fixIterations:  newHistogram(1, 2, 4, 8, 16, 32, 64, 128, 1024),
+		strengthDeltas: newHistogram(-100, -50, -25, -10, -5, -1, 0, 1, 5, 10, 25, 50, 100),
+	}
+}
+
+//MatchApplied implements Instrumentation
+func (m *PrometheusMetrics) MatchApplied(context string, elapsed time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.matches[matchKey{context: context, result: result}]++
+	m.applyDuration.observe(elapsed.Seconds())
+}
+
+//PeriodClosed implements Instrumentation
+func (m *PrometheusMetrics) PeriodClosed(context string, elapsed time.Duration, diagnostics rating.Diagnostics, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fixDuration.observe(elapsed.Seconds())
+	m.fixIterations.observe(float64(diagnostics.Iterations))
+	if err != nil {
+		m.fixErrors++
+	}
+	if !diagnostics.Converged {
+		m.unconverged++
+	}
+}
+
+//RatingChanged implements Instrumentation
+func (m *PrometheusMetrics) RatingChanged(context string, delta float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.strengthDeltas.observe(delta)
+}
+
+//ServeHTTP writes the metrics in the Prometheus text format
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	m.write(bw)
+	bw.Flush()
+}
+
+func (m *PrometheusMetrics) write(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]matchKey, 0, len(m.matches))
+	for key := range m.matches {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].context != keys[j].context {
+			return keys[i].context < keys[j].context
+		}
+		return keys[i].result < keys[j].result
+	})
+	fmt.Fprintln(w, "# HELP rating_matches_applied_total Number of Match.Apply calls.")
+	fmt.Fprintln(w, "# TYPE rating_matches_applied_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(w, "rating_matches_applied_total{context=\"%s\",result=\"%s\"} %d\n", labelValue(key.context), labelValue(key.result), m.matches[key])
+	}
+	m.applyDuration.write(w, "rating_apply_duration_seconds", "Latency of Match.Apply.")
+	m.fixDuration.write(w, "rating_fix_duration_seconds", "Latency of fixing a player rating at the end of the rating period.")
+	m.fixIterations.write(w, "rating_fix_solver_iterations", "Iterations of the volatility solver per fix.")
+	fmt.Fprintln(w, "# HELP rating_fix_errors_total Number of failed fixes.")
+	fmt.Fprintln(w, "# TYPE rating_fix_errors_total counter")
+	fmt.Fprintf(w, "rating_fix_errors_total %d\n", m.fixErrors)
+	fmt.Fprintln(w, "# HELP rating_fix_unconverged_total Number of fixes whose volatility solver did not converge.")
+	fmt.Fprintln(w, "# TYPE rating_fix_unconverged_total counter")
+	fmt.Fprintf(w, "rating_fix_unconverged_total %d\n", m.unconverged)
+	m.strengthDeltas.write(w, "rating_strength_delta", "Strength change of a player by an applied match on the configured Scale.")
+}
+
+//labelEscaper escapes a label value of the Prometheus text format, only backslash, double quote and line feed are escaped
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelValue(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+//histogram is the cumulative histogram of the Prometheus text format
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(bounds ...float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) write(w *bufio.Writer, name, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	for i, bound := range h.bounds {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
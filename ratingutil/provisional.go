@@ -0,0 +1,91 @@
+package ratingutil
+
+import (
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/pkg/errors"
+)
+
+//Provisional is the configuration of the provisional phase of new players.
+//In the phase, the rating is fixed after each game instead of at the end of the rating period,
+//so that a new player reaches a meaningful rating quickly.
+type Provisional struct {
+	//Games is the maximum number of games in the phase.
+	Games int
+	//Tau is the system parameter used for the fix after each game, usually higher than Config.Tau.
+	Tau float64
+	//Widening is the deviation on the configured Scale added after each game in the phase,
+	//so that the next game moves the rating more, like a higher K-factor.
+	Widening float64
+	//GraduationDeviation is the deviation on the configured Scale, below which the player graduates from the phase.
+	GraduationDeviation float64
+}
+
+//GraduationEvent is the event of a player leaving the provisional phase
+type GraduationEvent struct {
+	//Player is the player in the context that graduated.
+	Player *Player
+	At     time.Time
+	//Games is the number of games played in the phase.
+	Games  int
+	Rating rating.Rating
+}
+
+//WithProvisional is set Provisional to config.
+//A player is provisional for the first games games, or until the deviation falls below graduationDeviation.
+func (c *Config) WithProvisional(games int, tau, widening, graduationDeviation float64) *Config {
+	c.Provisional = &Provisional{
+		Games:               games,
+		Tau:                 tau,
+		Widening:            widening,
+		GraduationDeviation: graduationDeviation,
+	}
+	return c
+}
+
+//Games returns the number of games the player played
+func (p *Player) Games() int {
+	root := p.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	return p.games
+}
+
+//IsProvisional reports whether the player is in the provisional phase with the config
+func (p *Player) IsProvisional(config *Config) bool {
+	root := p.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	return p.isProvisional(config)
+}
+
+//isProvisional must be called with the player locked
+func (p *Player) isProvisional(config *Config) bool {
+	return config.Provisional != nil && !p.graduated && p.games < config.Provisional.Games
+}
+
+//played counts a game of the player after the match is applied, and fixes the rating in the provisional phase.
+func (p *Player) played(at time.Time, config *Config) error {
+	provisional := p.isProvisional(config)
+	p.games++
+	if !provisional {
+		return nil
+	}
+	if _, err := p.estimated.FixWithOptions(config.Provisional.Tau, config.fixOptions(p)); err != nil {
+		return errors.Wrapf(err, "provisional fix %s", p.name)
+	}
+	fixed := p.estimated.Fixed
+	if p.games < config.Provisional.Games && config.Scale.Deviation(fixed) >= config.Provisional.GraduationDeviation {
+		p.estimated.Restore(rating.NewEstimated(config.widen(fixed, config.Provisional.Widening)))
+		return nil
+	}
+	p.graduated = true
+	config.graduated(&GraduationEvent{
+		Player: p,
+		At:     at,
+		Games:  p.games,
+		Rating: fixed,
+	})
+	return nil
+}
@@ -0,0 +1,98 @@
+package ratingutil_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mashiike/rating/ratingutil"
+)
+
+func TestProvisional(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	newService := func(provisional bool) *ratingutil.Service {
+		config := ratingutil.NewConfig().WithClock(clock).WithRatingPeriod(ratingutil.PeriodWeek)
+		if provisional {
+			config.WithProvisional(5, 1.2, 100.0, 100.0)
+		}
+		return ratingutil.New(config)
+	}
+	normal := newService(false)
+	accelerated := newService(true)
+	var graduations []*ratingutil.GraduationEvent
+	accelerated.Hooks.OnGraduate(func(e *ratingutil.GraduationEvent) {
+		if e.Player.Name() == "fast" {
+			graduations = append(graduations, e)
+		}
+	})
+
+	play := func(svc *ratingutil.Service, rookie *ratingutil.Player) {
+		for i := 0; i < 8; i++ {
+			clock.now = clock.now.Add(time.Hour)
+			opponent := svc.NewPlayerOnScale("veteran", 1800.0, 50.0, clock.now)
+			match, _ := svc.NewMatch(rookie, opponent)
+			match.Add(rookie, 1.0)
+			if err := svc.Apply(match); err != nil {
+				t.Fatal(err)
+			}
+			if i == 0 && svc == accelerated && !rookie.IsProvisional(svc.Config) {
+				t.Error("rookie is not provisional after the first game")
+			}
+		}
+	}
+	slow := normal.NewDefaultPlayer("slow")
+	fast := accelerated.NewDefaultPlayer("fast")
+	play(normal, slow)
+	play(accelerated, fast)
+
+	if fast.Games() != 8 || slow.Games() != 8 {
+		t.Errorf("games = %d, %d", fast.Games(), slow.Games())
+	}
+	if fast.IsProvisional(accelerated.Config) {
+		t.Error("fast is provisional after 8 games")
+	}
+	if len(graduations) != 1 {
+		t.Fatalf("graduations = %d", len(graduations))
+	}
+	if g := graduations[0]; g.Player != fast || g.Games > 5 || g.Rating.Deviation() >= 350.0 {
+		t.Errorf("graduation = %+v", g)
+	}
+	if g := graduations[0]; g.Rating.Strength() <= 1800.0 {
+		t.Errorf("graduation rating = %v, want above the beaten veterans", g.Rating)
+	}
+	//the in-period estimate keeps the expected score of the initial rating, so it overshoots
+	if fast.Rating().Strength() >= slow.Rating().Strength() {
+		t.Errorf("provisional rating %v is not below the in-period estimate %v", fast.Rating(), slow.Rating())
+	}
+}
+
+func TestProvisionalConcurrentRead(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig().WithProvisional(5, 1.2, 100.0, 100.0))
+	rookie := svc.NewDefaultPlayer("rookie")
+	veteran := svc.NewDefaultPlayer("veteran")
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < 8; i++ {
+			match, _ := svc.NewMatch(rookie, veteran)
+			match.Add(rookie, 1.0)
+			if err := svc.Apply(match); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	for running := true; running; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			running = false
+		default:
+			_, _ = rookie.Games(), rookie.IsProvisional(svc.Config)
+		}
+	}
+	if rookie.Games() != 8 {
+		t.Errorf("games = %d", rookie.Games())
+	}
+}
@@ -1,6 +1,7 @@
 package ratingutil
 
 import (
+	"sync"
 	"time"
 
 	"github.com/mashiike/rating"
@@ -8,8 +9,12 @@ import (
 )
 
 //Service is usecase
+//Service is safe for concurrent use, as long as the Config is not changed.
 type Service struct {
 	*Config
+
+	resultsMu sync.Mutex
+	results   *Ledger
 }
 
 //New creates a service class for this package.
@@ -21,22 +26,44 @@ func New(config *Config) *Service {
 
 //NewPlayer is constractor of *Player
 func (s *Service) NewPlayer(name string, fixed rating.Rating, fixedAt time.Time) *Player {
-	return &Player{
+	p := &Player{
+		seq:       nextPlayerSeq(),
 		name:      name,
 		estimated: rating.NewEstimated(fixed),
 		fixedAt:   fixedAt.Truncate(s.Config.RatingPeriod),
 	}
+	s.Config.Hooks.firePlayerCreated(p)
+	return p
 }
 
 //NewDefaultPlayer is factory of default player
 func (s *Service) NewDefaultPlayer(name string) *Player {
 	return s.NewPlayer(
 		name,
-		rating.Default(s.Config.InitialVolatility()),
+		s.Config.Scale.Default(s.Config.InitialVolatility()),
 		s.Config.Now(),
 	)
 }
 
+//NewPlayerOnScale is constractor of *Player with the strength and deviation on the configured Scale
+func (s *Service) NewPlayerOnScale(name string, strength, deviation float64, fixedAt time.Time) *Player {
+	return s.NewPlayer(
+		name,
+		s.Config.Scale.New(strength, deviation, s.Config.InitialVolatility()),
+		fixedAt,
+	)
+}
+
+//Format returns a textual representation of the Team / Player rating on the configured Scale
+func (s *Service) Format(element Element, layout string) string {
+	return s.Config.Scale.Format(element.Rating(), layout)
+}
+
+//Parse parses a formatted rating on the configured Scale
+func (s *Service) Parse(layout, value string) (rating.Rating, error) {
+	return s.Config.Scale.ParseWithVolatility(layout, value, s.Config.InitialVolatility())
+}
+
 //Players is an array of players
 type Players []*Player
 
@@ -60,11 +87,26 @@ func (s *Service) NewMatch(elements ...Element) (*Match, error) {
 	return &Match{
 		scores:        scores,
 		applyStrategy: s.Config.DefaultApplyStrategy,
+		config:        s.Config,
 	}, nil
 }
 
+//NewMatchInContext is like NewMatch, but the match is played in the player context, such as game mode, map or role.
+func (s *Service) NewMatchInContext(key string, elements ...Element) (*Match, error) {
+	match, err := s.NewMatch(elements...)
+	if err != nil {
+		return nil, err
+	}
+	match.context = key
+	return match, nil
+}
+
 //ApplyWithTime is a function for apply Match for Team/Player outcome.
+//A result in an already closed rating period is handled by Config.LatePolicy.
 func (s *Service) ApplyWithTime(match *Match, outcomeAt time.Time) error {
+	if results := s.retained(); results != nil {
+		return results.recordMatch(match, outcomeAt)
+	}
 	return match.Apply(outcomeAt, s.Config)
 }
 
@@ -72,3 +114,21 @@ func (s *Service) ApplyWithTime(match *Match, outcomeAt time.Time) error {
 func (s *Service) Apply(match *Match) error {
 	return s.ApplyWithTime(match, s.Config.Now())
 }
+
+//Preview returns the projected Rating of each Team / Player for each outcome, without any side effects.
+func (s *Service) Preview(match *Match) (map[Element]rating.Preview, error) {
+	return match.Preview(s.Config)
+}
+
+//History returns the rating time series of the player by Player.ID, the name of the player unless WithID is set.
+//The exports of the series are on the configured Scale.
+func (s *Service) History(id string) (RatingSeries, error) {
+	if s.Config.History == nil {
+		return nil, errors.New("history is not configured")
+	}
+	series, err := s.Config.History.Series(id)
+	if err != nil {
+		return nil, err
+	}
+	return series.OnScale(s.Config.Scale), nil
+}
@@ -0,0 +1,151 @@
+package ratingutil_test
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mashiike/rating/ratingutil"
+)
+
+func TestServiceConcurrentApply(t *testing.T) {
+	svc := ratingutil.New(ratingutil.NewConfig().WithHistory(ratingutil.NewMemoryHistory()))
+	players := make(ratingutil.Players, 0, 6)
+	for i := 0; i < cap(players); i++ {
+		players = append(players, svc.NewDefaultPlayer(fmt.Sprintf("player%d", i)))
+	}
+	team1 := svc.NewTeam("team1", players[0:2])
+	team2 := svc.NewTeam("team2", players[1:3])
+
+	var wg sync.WaitGroup
+	const n = 50
+	for i := 0; i < n; i++ {
+		wg.Add(3)
+		//players[1] joins both teams, and the order of elements differs between goroutines.
+		go func(i int) {
+			defer wg.Done()
+			match, _ := svc.NewMatch(team1, players[3])
+			match.Add(team1, float64(i%2))
+			if err := svc.Apply(match); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			match, _ := svc.NewMatch(players[3], team2, players[4])
+			match.Add(players[4], float64(i%3))
+			if err := svc.Apply(match); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			match, _ := svc.NewMatch(players[5], players[0])
+			_ = match.WinProbs()
+			_ = players[1].Rating()
+		}()
+	}
+	wg.Wait()
+
+	series, err := svc.History("player1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 2*n {
+		t.Errorf("unexpected history length: %d", len(series))
+	}
+}
+
+func BenchmarkServiceApplyContention(b *testing.B) {
+	svc := ratingutil.New(ratingutil.NewConfig())
+	players := make(ratingutil.Players, 0, 8)
+	for i := 0; i < cap(players); i++ {
+		players = append(players, svc.NewDefaultPlayer(fmt.Sprintf("player%d", i)))
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i++
+			match, _ := svc.NewMatch(players[i%len(players)], players[(i+1)%len(players)])
+			match.Add(players[i%len(players)], 1.0)
+			if err := svc.Apply(match); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+func TestServiceSafeguards(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	svc := ratingutil.New(
+		ratingutil.NewConfig().
+			WithClock(clock).
+			WithRatingPeriod(ratingutil.PeriodDay).
+			WithMinDeviation(120.0).
+			WithMaxChangePerPeriod(100.0).
+			WithStrengthFloor(1200.0),
+	)
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	goat.SetFloor(1450.0)
+	prev := sheep.Rating()
+	for i := 0; i < 20; i++ {
+		match, _ := svc.NewMatch(sheep, goat)
+		match.Add(sheep, 1.0)
+		if err := svc.Apply(match); err != nil {
+			t.Fatal(err)
+		}
+		clock.now = clock.now.Add(ratingutil.PeriodDay + time.Hour)
+		//close the period
+		if err := sheep.Prepare(clock.now, svc.Config); err != nil {
+			t.Fatal(err)
+		}
+		if err := goat.Prepare(clock.now, svc.Config); err != nil {
+			t.Fatal(err)
+		}
+		if diff := sheep.Rating().Diff(prev); diff > 100.0+1e-9 {
+			t.Errorf("period %d: change %v exceeds the max change", i, diff)
+		}
+		prev = sheep.Rating()
+	}
+	if got := sheep.Rating().Deviation(); got < 120.0 {
+		t.Errorf("deviation %v is below the min deviation", got)
+	}
+	if got := goat.Rating().Strength(); got < 1450.0 {
+		t.Errorf("strength %v is below the player floor", got)
+	}
+}
+
+func TestServiceConfigLiteral(t *testing.T) {
+	//a Config without NewConfig has the zero Scale, it is the Glicko-2 factor
+	clock := &fixedClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	svc := ratingutil.New(&ratingutil.Config{
+		Clock:                  clock,
+		Tau:                    0.5,
+		RatingPeriod:           ratingutil.PeriodDay,
+		PeriodToResetDeviation: ratingutil.PeriodYear,
+		DefaultApplyStrategy:   ratingutil.AsRoundrobin,
+		MinDeviation:           50.0,
+		StrengthFloor:          1000.0,
+		HasStrengthFloor:       true,
+		ContextWidening:        100.0,
+	})
+	sheep := svc.NewDefaultPlayer("sheep")
+	goat := svc.NewDefaultPlayer("goat")
+	match, _ := svc.NewMatchInContext("ranked", sheep, goat)
+	match.Add(sheep, 1.0)
+	if err := svc.Apply(match); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(2 * ratingutil.PeriodDay)
+	if err := sheep.Context("ranked", svc.Config).Prepare(clock.now, svc.Config); err != nil {
+		t.Fatal(err)
+	}
+	r := sheep.Context("ranked", svc.Config).Rating()
+	if math.IsNaN(r.Mu()) || math.IsInf(r.Mu(), 0) || r.Mu() <= 0.0 {
+		t.Errorf("unexpected rating: %v", r)
+	}
+}
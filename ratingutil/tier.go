@@ -0,0 +1,222 @@
+package ratingutil
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/mashiike/rating"
+)
+
+//Tier is a rank of players such as Bronze, Silver, ... Grandmaster
+type Tier struct {
+	Name string
+	//Min is the minimum display rating of the tier
+	Min float64
+	//Divisions is the number of divisions in the tier, the division 1 is the highest.
+	//If 1 or less, the tier has no divisions.
+	Divisions int
+}
+
+//TierSystem maps the uncertainty-aware display rating to tiers and divisions.
+//
+//  display rating = Strength - K * Deviation
+//
+//Players do not flip tiers on each game, because demotion requires to fall below the division by Hysteresis.
+type TierSystem struct {
+	//Tiers in ascending order of Min
+	Tiers []Tier
+	//K is the weight of the deviation in the display rating, 0 means the strength itself.
+	K float64
+	//Hysteresis is the margin of the display rating for demotion.
+	Hysteresis float64
+	//While the deviation is above PlacementDeviation, the player is in placement matches.
+	PlacementDeviation float64
+	//Scale is the presentation of the ratings
+	Scale rating.Scale
+}
+
+//NewTierSystem is constractor of *TierSystem
+//The default is K=2, Hysteresis=25 and PlacementDeviation=150 on the default scale.
+func NewTierSystem(tiers ...Tier) *TierSystem {
+	sorted := make([]Tier, len(tiers))
+	copy(sorted, tiers)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Min < sorted[j].Min })
+	return &TierSystem{
+		Tiers:              sorted,
+		K:                  2.0,
+		Hysteresis:         25.0,
+		PlacementDeviation: 150.0,
+		Scale:              rating.DefaultScale,
+	}
+}
+
+//Standing is the position of a player in the TierSystem
+type Standing struct {
+	//Placement is true while the player is in placement matches, and the tier is not determined.
+	Placement bool
+	Tier      string
+	//TierIndex is the index of TierSystem.Tiers, -1 if below the lowest tier or in placement.
+	TierIndex int
+	//Division is 1 for the highest division of the tier, 0 if the tier has no divisions.
+	Division int
+	//Progress is from 0 to 1, the progress to the next division or tier.
+	//In placement, it is the progress to the end of the placement.
+	Progress      float64
+	DisplayRating float64
+}
+
+//String is implements fmt.Stringer
+func (s Standing) String() string {
+	switch {
+	case s.Placement:
+		return fmt.Sprintf("Placement(%.0f%%)", s.Progress*100.0)
+	case s.TierIndex < 0:
+		return "Unranked"
+	case s.Division == 0:
+		return fmt.Sprintf("%s(%.0f%%)", s.Tier, s.Progress*100.0)
+	}
+	return fmt.Sprintf("%s %d(%.0f%%)", s.Tier, s.Division, s.Progress*100.0)
+}
+
+//DisplayRating returns the conservative rating, Strength - K * Deviation
+func (ts *TierSystem) DisplayRating(r rating.Rating) float64 {
+	return ts.Scale.Strength(r) - ts.K*ts.Scale.Deviation(r)
+}
+
+//Standing returns the position of the rating without the previous standing.
+func (ts *TierSystem) Standing(r rating.Rating) Standing {
+	if placement, ok := ts.placement(r); ok {
+		return placement
+	}
+	display := ts.DisplayRating(r)
+	return ts.standingAt(ts.levelOf(display), display)
+}
+
+//Update returns the new position from the previous standing.
+//Promotion is immediate, but demotion requires to fall below the previous division by Hysteresis.
+func (ts *TierSystem) Update(prev Standing, r rating.Rating) Standing {
+	if placement, ok := ts.placement(r); ok {
+		return placement
+	}
+	display := ts.DisplayRating(r)
+	level := ts.levelOf(display)
+	if !prev.Placement && prev.TierIndex >= 0 {
+		prevLevel := ts.level(prev.TierIndex, prev.Division)
+		if level < prevLevel && display >= ts.lowerBound(prevLevel)-ts.Hysteresis {
+			level = prevLevel
+		}
+	}
+	return ts.standingAt(level, display)
+}
+
+//Floor returns the minimum display rating of the standing tier.
+//If the standing has no tier, ok is false.
+func (ts *TierSystem) Floor(s Standing) (floor float64, ok bool) {
+	if s.Placement || s.TierIndex < 0 || s.TierIndex >= len(ts.Tiers) {
+		return 0.0, false
+	}
+	return ts.Tiers[s.TierIndex].Min, true
+}
+
+//StrengthFloor returns the strength on the Scale that keeps the display rating of r at the floor of the standing tier,
+//Floor + K * Deviation. It is for Player.SetFloor, which bounds the strength rather than the display rating.
+//If the standing has no tier, ok is false.
+func (ts *TierSystem) StrengthFloor(s Standing, r rating.Rating) (floor float64, ok bool) {
+	floor, ok = ts.Floor(s)
+	if !ok {
+		return 0.0, false
+	}
+	return floor + ts.K*ts.Scale.Deviation(r), true
+}
+
+func (ts *TierSystem) placement(r rating.Rating) (Standing, bool) {
+	deviation := ts.Scale.Deviation(r)
+	if deviation <= ts.PlacementDeviation {
+		return Standing{}, false
+	}
+	initial := ts.Scale.InitialDeviation()
+	progress := 0.0
+	if initial > ts.PlacementDeviation {
+		progress = math.Min(math.Max((initial-deviation)/(initial-ts.PlacementDeviation), 0.0), 1.0)
+	}
+	return Standing{
+		Placement:     true,
+		TierIndex:     -1,
+		Progress:      progress,
+		DisplayRating: ts.DisplayRating(r),
+	}, true
+}
+
+//tierLevel is a division of the tier, levels are numbered from the bottom.
+type tierLevel struct {
+	tier     int
+	division int
+	lower    float64
+	upper    float64
+}
+
+func (ts *TierSystem) levels() []tierLevel {
+	levels := make([]tierLevel, 0, len(ts.Tiers)*2)
+	for i, tier := range ts.Tiers {
+		upper := math.Inf(1)
+		if i+1 < len(ts.Tiers) {
+			upper = ts.Tiers[i+1].Min
+		} else if i > 0 {
+			//the top tier has the same width as the previous one for divisions and progress
+			upper = tier.Min + (tier.Min - ts.Tiers[i-1].Min)
+		}
+		if tier.Divisions <= 1 || math.IsInf(upper, 1) {
+			levels = append(levels, tierLevel{tier: i, lower: tier.Min, upper: upper})
+			continue
+		}
+		width := (upper - tier.Min) / float64(tier.Divisions)
+		for d := tier.Divisions; d >= 1; d-- {
+			lower := tier.Min + width*float64(tier.Divisions-d)
+			levels = append(levels, tierLevel{tier: i, division: d, lower: lower, upper: lower + width})
+		}
+	}
+	return levels
+}
+
+//levelOf returns the index of levels, -1 if below the lowest tier
+func (ts *TierSystem) levelOf(display float64) int {
+	levels := ts.levels()
+	for i := len(levels) - 1; i >= 0; i-- {
+		if display >= levels[i].lower {
+			return i
+		}
+	}
+	return -1
+}
+
+func (ts *TierSystem) level(tier, division int) int {
+	for i, l := range ts.levels() {
+		if l.tier == tier && l.division == division {
+			return i
+		}
+	}
+	return -1
+}
+
+func (ts *TierSystem) lowerBound(level int) float64 {
+	return ts.levels()[level].lower
+}
+
+func (ts *TierSystem) standingAt(level int, display float64) Standing {
+	if level < 0 {
+		return Standing{TierIndex: -1, DisplayRating: display}
+	}
+	l := ts.levels()[level]
+	progress := 0.0
+	if !math.IsInf(l.upper, 1) && l.upper > l.lower {
+		progress = math.Min(math.Max((display-l.lower)/(l.upper-l.lower), 0.0), 1.0)
+	}
+	return Standing{
+		Tier:          ts.Tiers[l.tier].Name,
+		TierIndex:     l.tier,
+		Division:      l.division,
+		Progress:      progress,
+		DisplayRating: display,
+	}
+}
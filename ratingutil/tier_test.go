@@ -0,0 +1,70 @@
+package ratingutil_test
+
+import (
+	"testing"
+
+	"github.com/mashiike/rating"
+	"github.com/mashiike/rating/ratingutil"
+)
+
+func newTestTierSystem() *ratingutil.TierSystem {
+	return ratingutil.NewTierSystem(
+		ratingutil.Tier{Name: "Silver", Min: 1200.0, Divisions: 2},
+		ratingutil.Tier{Name: "Bronze", Min: 1000.0, Divisions: 2},
+		ratingutil.Tier{Name: "Gold", Min: 1400.0, Divisions: 1},
+	)
+}
+
+func TestTierSystemStanding(t *testing.T) {
+	ts := newTestTierSystem()
+	cases := []struct {
+		strength  float64
+		deviation float64
+		expected  string
+	}{
+		{1500.0, 350.0, "Placement(0%)"},
+		{1500.0, 250.0, "Placement(50%)"},
+		{1100.0, 100.0, "Unranked"},
+		{1250.0, 50.0, "Bronze 1(50%)"},
+		{1450.0, 50.0, "Silver 1(50%)"},
+		{1600.0, 50.0, "Gold(50%)"},
+		{2000.0, 50.0, "Gold(100%)"},
+	}
+	for _, c := range cases {
+		got := ts.Standing(rating.New(c.strength, c.deviation, 0.06))
+		if got.String() != c.expected {
+			t.Errorf("%v: got %v, expected %v", c, got, c.expected)
+		}
+	}
+}
+
+func TestTierSystemUpdate(t *testing.T) {
+	ts := newTestTierSystem()
+	prev := ts.Standing(rating.New(1310.0, 50.0, 0.06))
+	if prev.Tier != "Silver" || prev.Division != 2 {
+		t.Fatalf("unexpected standing: %v", prev)
+	}
+	//just below the division, kept by hysteresis
+	got := ts.Update(prev, rating.New(1290.0, 50.0, 0.06))
+	if got.Tier != "Silver" || got.Division != 2 || got.Progress != 0.0 {
+		t.Errorf("unexpected demotion: %v", got)
+	}
+	got = ts.Update(prev, rating.New(1270.0, 50.0, 0.06))
+	if got.Tier != "Bronze" || got.Division != 1 {
+		t.Errorf("unexpected standing: %v", got)
+	}
+	//promotion is immediate
+	got = ts.Update(prev, rating.New(1410.0, 50.0, 0.06))
+	if got.Tier != "Silver" || got.Division != 1 {
+		t.Errorf("unexpected promotion: %v", got)
+	}
+	if floor, ok := ts.Floor(got); !ok || floor != 1200.0 {
+		t.Errorf("unexpected floor: %v", floor)
+	}
+	if floor, ok := ts.StrengthFloor(got, rating.New(1410.0, 50.0, 0.06)); !ok || floor != 1300.0 {
+		t.Errorf("unexpected strength floor: %v", floor)
+	}
+	if _, ok := ts.StrengthFloor(ts.Standing(rating.New(1500.0, 350.0, 0.06)), rating.New(1500.0, 350.0, 0.06)); ok {
+		t.Error("placement should have no strength floor")
+	}
+}
@@ -0,0 +1,116 @@
+package ratingutil
+
+import (
+	"time"
+
+	"github.com/mashiike/rating"
+	"github.com/pkg/errors"
+)
+
+//transaction saves the state of the players before operating them,
+//and restores it when the operation fails on the way.
+type transaction struct {
+	base      *Config
+	config    *Config
+	history   *historyBuffer
+	events    *eventBuffer
+	snapshots []playerSnapshot
+	//created are the contexts created by the operation, they are unregistered by rollback
+	created Players
+}
+
+type playerSnapshot struct {
+	player    *Player
+	estimated *rating.Estimated
+	fixedAt   time.Time
+	games     int
+	graduated bool
+}
+
+func begin(players Players, config *Config) *transaction {
+	tx := &transaction{
+		base:      config,
+		snapshots: make([]playerSnapshot, 0, len(players)),
+	}
+	for _, player := range players {
+		tx.snapshots = append(tx.snapshots, playerSnapshot{
+			player:    player,
+			estimated: player.estimated.Clone(),
+			fixedAt:   player.fixedAt,
+			games:     player.games,
+			graduated: player.graduated,
+		})
+	}
+	//side effects other than players are deferred until commit
+	c := *config
+	if config.History != nil {
+		tx.history = &historyBuffer{HistoryStore: config.History}
+		c.History = tx.history
+	}
+	if config.Hooks != nil {
+		tx.events = &eventBuffer{}
+		c.events = tx.events
+	}
+	tx.config = &c
+	return tx
+}
+
+func (tx *transaction) rollback() {
+	for _, s := range tx.snapshots {
+		s.player.estimated.Restore(s.estimated)
+		s.player.fixedAt = s.fixedAt
+		s.player.games = s.games
+		s.player.graduated = s.graduated
+	}
+	unregisterContexts(tx.created)
+}
+
+func (tx *transaction) commit() error {
+	if tx.history != nil {
+		return tx.history.flush()
+	}
+	return nil
+}
+
+//fire calls the hooks of the committed events, it must be called after the players are unlocked
+func (tx *transaction) fire() {
+	if tx.events == nil {
+		return
+	}
+	for _, e := range tx.events.fixes {
+		tx.base.Hooks.fireFix(e)
+	}
+	for _, e := range tx.events.graduations {
+		tx.base.Hooks.fireGraduate(e)
+	}
+}
+
+//historyBuffer holds the points until the transaction is committed
+type historyBuffer struct {
+	HistoryStore
+	names  []string
+	points []HistoryPoint
+}
+
+func (h *historyBuffer) Record(name string, point HistoryPoint) error {
+	h.names = append(h.names, name)
+	h.points = append(h.points, point)
+	return nil
+}
+
+//flush records the points, the recorded points are removed again if one of them fails
+func (h *historyBuffer) flush() error {
+	for i, point := range h.points {
+		if err := h.HistoryStore.Record(h.names[i], point); err != nil {
+			recorded := make(map[string][]HistoryPoint)
+			for j := 0; j < i; j++ {
+				recorded[h.names[j]] = append(recorded[h.names[j]], h.points[j])
+			}
+			if rerr := removeHistory(h.HistoryStore, recorded, nil); rerr != nil {
+				return errors.Wrapf(err, "failed remove recorded history: %v", rerr)
+			}
+			return err
+		}
+	}
+	return nil
+}
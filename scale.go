@@ -0,0 +1,168 @@
+package rating
+
+import "math"
+
+// Scale is the presentation of Rating as general values.
+// The rating is calculated on the Glicko-2 scale, and Scale converts it to the display scale as follows.
+//
+//   strength  = mu * Factor + Center
+//   deviation = phi * Factor
+//
+// The default is Center=1500 and Factor=173.7178 of ref[1].
+type Scale struct {
+	// Center is the strength of mu = 0, and the strength of the starting Player/Team.
+	Center float64
+	// Factor is the strength per unit of mu. if zero, 173.7178 of ref[1].
+	Factor float64
+
+	// MinStrength and MaxStrength clamp the strength.
+	// if infinity, or MinStrength is not less than MaxStrength such as both zero, no clamp.
+	MinStrength float64
+	MaxStrength float64
+	// MinDeviation is a floor of the displayed deviation. if zero, no floor.
+	MinDeviation float64
+}
+
+// DefaultScale is the scale used by New, Rating.Strength, Rating.Format, Parse and so on.
+// Change it only at initialization, it is not safe for concurrent use.
+var DefaultScale = Scale{
+	Center:      centerValue,
+	Factor:      convartRate,
+	MinStrength: math.Inf(-1),
+	MaxStrength: math.Inf(1),
+}
+
+// NewScale is constractor of Scale.
+// Factor is determined so that initialDeviation is the deviation of the starting Player/Team.
+// for example, NewScale(25.0, 25.0/3.0) is TrueSkill-like scale.
+func NewScale(center, initialDeviation float64) Scale {
+	return Scale{
+		Center:      center,
+		Factor:      initialDeviation / startPhi,
+		MinStrength: math.Inf(-1),
+		MaxStrength: math.Inf(1),
+	}
+}
+
+// WithStrengthBounds returns the scale which clamps the strength between min and max.
+func (s Scale) WithStrengthBounds(min, max float64) Scale {
+	s.MinStrength = min
+	s.MaxStrength = max
+	return s
+}
+
+// WithDeviationFloor returns the scale whose displayed deviation is min or more.
+func (s Scale) WithDeviationFloor(min float64) Scale {
+	s.MinDeviation = min
+	return s
+}
+
+// InitialDeviation is the deviation of the starting Player/Team on this scale.
+func (s Scale) InitialDeviation() float64 {
+	return startPhi * s.factor()
+}
+
+// New is a constractor for Rating with the values on this scale.
+func (s Scale) New(strength, deviation, volatility float64) Rating {
+	return Rating{
+		mu:    s.Mu(s.clampStrength(strength)),
+		phi:   s.Phi(deviation),
+		sigma: volatility,
+	}
+}
+
+// Mu converts the strength on this scale to mu on the Glicko-2 scale, without the clamp.
+func (s Scale) Mu(strength float64) float64 {
+	return (strength - s.Center) / s.factor()
+}
+
+// Phi converts the deviation on this scale to phi on the Glicko-2 scale.
+// A strength difference is converted by it as well.
+func (s Scale) Phi(deviation float64) float64 {
+	return deviation / s.factor()
+}
+
+// Widen returns the rating whose RD is spread by deviation on this scale, as sqrt(RD^2 + deviation^2).
+func (s Scale) Widen(r Rating, deviation float64) Rating {
+	r.phi = math.Hypot(r.phi, s.Phi(deviation))
+	return r
+}
+
+// Shift returns the rating whose strength on this scale is moved by diff.
+func (s Scale) Shift(r Rating, diff float64) Rating {
+	r.mu += s.Phi(diff)
+	return r
+}
+
+// Diff returns the strength difference r - o on this scale without truncation.
+func (s Scale) Diff(r, o Rating) float64 {
+	return (r.mu - o.mu) * s.factor()
+}
+
+// Default is return default rating for starting Player/Team on this scale.
+func (s Scale) Default(volatility float64) Rating {
+	return Rating{
+		mu:    0.0,
+		phi:   startPhi,
+		sigma: volatility,
+	}
+}
+
+// Strength is return value of strength on this scale without truncation.
+func (s Scale) Strength(r Rating) float64 {
+	return s.clampStrength(r.mu*s.factor() + s.Center)
+}
+
+// Deviation is return RD on this scale without truncation.
+func (s Scale) Deviation(r Rating) float64 {
+	return math.Max(r.phi*s.factor(), s.MinDeviation)
+}
+
+// Interval is return value of strength 95% confidence interval on this scale.
+func (s Scale) Interval(r Rating) (float64, float64) {
+	strength := s.Strength(r)
+	rd2 := s.Deviation(r) * 2
+	return strength - rd2, strength + rd2
+}
+
+// IntervalAt returns the strength interval at the confidence level on this scale, such as 0.99.
+func (s Scale) IntervalAt(r Rating, level float64) (float64, float64) {
+	strength := s.Strength(r)
+	diff := ZScore(level) * s.Deviation(r)
+	return strength - diff, strength + diff
+}
+
+// Format returns a textual representation of the rating value on this scale.
+func (s Scale) Format(r Rating, layout string) string {
+	return string(s.AppendFormat(r, make([]byte, 0, len(layout)+10), layout))
+}
+
+// AppendFormat is like Format but appends the textual.
+func (s Scale) AppendFormat(r Rating, b []byte, layout string) []byte {
+	return appendFormat(s, r, nil, b, layout)
+}
+
+// Parse parses a formatted string on this scale and returns the rating value it represents.
+// if not include volatility in layout, volatility set 0.06
+func (s Scale) Parse(layout, value string) (Rating, error) {
+	return parse(s, layout, value, ParseOptions{DefaultVolatility: 0.06})
+}
+
+// ParseWithVolatility is parse a formatted string on this scale with default volatility.
+func (s Scale) ParseWithVolatility(layout, value string, volatility float64) (Rating, error) {
+	return parse(s, layout, value, ParseOptions{DefaultVolatility: volatility})
+}
+
+func (s Scale) clampStrength(strength float64) float64 {
+	if s.MinStrength >= s.MaxStrength {
+		return strength
+	}
+	return math.Min(math.Max(strength, s.MinStrength), s.MaxStrength)
+}
+
+func (s Scale) factor() float64 {
+	if s.Factor == 0.0 {
+		return convartRate
+	}
+	return s.Factor
+}
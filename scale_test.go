@@ -0,0 +1,70 @@
+package rating_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mashiike/rating"
+)
+
+func TestScale(t *testing.T) {
+	trueskill := rating.NewScale(25.0, 25.0/3.0)
+	r := trueskill.Default(0.06)
+	if got := trueskill.Strength(r); got != 25.0 {
+		t.Errorf("Strength got %v", got)
+	}
+	if got := trueskill.Deviation(r); math.Abs(got-25.0/3.0) > 1e-9 {
+		t.Errorf("Deviation got %v", got)
+	}
+	if r != rating.Default(0.06) {
+		t.Errorf("default rating should be independent of the scale: %v", r)
+	}
+	if got := trueskill.Format(r, rating.PlusMinusFormat); got != "25.0p-16.7" {
+		t.Errorf("Format got %q", got)
+	}
+	parsed, err := trueskill.Parse(rating.CSVFormat, "30.0,5.0,0.06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := trueskill.Strength(parsed); got != 30.0 {
+		t.Errorf("parsed Strength got %v", got)
+	}
+	if got := rating.DefaultScale.Format(parsed, rating.CSVFormat); got != rating.New(1500.0+5.0*(350.0*3.0/25.0), 5.0*(350.0*3.0/25.0), 0.06).Format(rating.CSVFormat) {
+		t.Errorf("same rating on default scale got %q", got)
+	}
+
+	bounded := rating.NewScale(1500.0, 350.0).WithStrengthBounds(0.0, 3000.0).WithDeviationFloor(30.0)
+	if got := bounded.Strength(rating.New(-200.0, 10.0, 0.06)); got != 0.0 {
+		t.Errorf("clamped Strength got %v", got)
+	}
+	if got := bounded.Strength(bounded.New(3500.0, 10.0, 0.06)); got != 3000.0 {
+		t.Errorf("clamped New got %v", got)
+	}
+	if got := bounded.Deviation(rating.New(1500.0, 10.0, 0.06)); got != 30.0 {
+		t.Errorf("floored Deviation got %v", got)
+	}
+}
+
+func TestScaleZeroValues(t *testing.T) {
+	//a literal without the bounds does not clamp
+	trueskill := rating.Scale{Center: 25.0, Factor: rating.NewScale(25.0, 25.0/3.0).Factor}
+	r := trueskill.New(30.0, 5.0, 0.06)
+	if got := trueskill.Format(r, rating.CSVFormat); got != "30.0,5.0,0.06" {
+		t.Errorf("Format got %q", got)
+	}
+	if got := trueskill.WithStrengthBounds(0.0, 50.0).Strength(trueskill.New(-10.0, 5.0, 0.06)); got != 0.0 {
+		t.Errorf("clamped Strength got %v", got)
+	}
+	//a literal without the factor is the Glicko-2 factor
+	centered := rating.Scale{}
+	r = centered.New(100.0, 350.0, 0.06)
+	if got := rating.DefaultScale.Strength(r); math.Abs(got-1600.0) > 1e-9 {
+		t.Errorf("zero factor Strength got %v", got)
+	}
+	if got := centered.InitialDeviation(); got != rating.InitialDeviation {
+		t.Errorf("zero factor InitialDeviation got %v", got)
+	}
+	if got := centered.Mu(rating.DefaultScale.Factor); got != 1.0 {
+		t.Errorf("Mu got %v", got)
+	}
+}
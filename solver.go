@@ -0,0 +1,266 @@
+package rating
+
+import (
+	"fmt"
+	"math"
+)
+
+// Solver is a root-finding algorithm for the new volatility at the end of the rating period.
+type Solver int
+
+// Solver constants
+const (
+	// Illinois is the algorithm used in ref[1], the default.
+	Illinois Solver = iota
+	// Brent is the Brent-Dekker method.
+	Brent
+	// Newton is the Newton-Raphson method, starting at the current volatility.
+	Newton
+)
+
+// String is implements fmt.Stringer
+func (s Solver) String() string {
+	switch s {
+	case Illinois:
+		return "illinois"
+	case Brent:
+		return "brent"
+	case Newton:
+		return "newton"
+	}
+	return fmt.Sprintf("Solver(%d)", int(s))
+}
+
+// FixOptions is the options of Estimated.FixWithOptions.
+// The zero value is the same as Estimated.Fix.
+type FixOptions struct {
+	Solver Solver
+	// Tolerance is the end condition of the iteration, if zero 0.000001.
+	Tolerance float64
+	// MaxIterations truncates the iteration, if zero 100000.
+	MaxIterations int
+
+	// Safeguards, the values are on the Glicko-2 scale.
+	// They are enforced even if there are no matches in the rating period.
+
+	// MinPhi is a floor of the deviation. if zero, no floor.
+	MinPhi float64
+	// MaxChange is the maximum change of mu per rating period. if zero, no limit.
+	MaxChange float64
+	// Floor is the mu that the rating can not drop below, enabled when HasFloor is true.
+	// A rating already below the floor is not raised, and can not drop further.
+	Floor    float64
+	HasFloor bool
+}
+
+// guard enforces the safeguards on the new rating.
+func (opts FixOptions) guard(before, after Rating) Rating {
+	if after.phi < opts.MinPhi {
+		after.phi = opts.MinPhi
+	}
+	if opts.HasFloor {
+		after.mu = math.Max(after.mu, math.Min(before.mu, opts.Floor))
+	}
+	if opts.MaxChange > 0 {
+		after.mu = math.Min(math.Max(after.mu, before.mu-opts.MaxChange), before.mu+opts.MaxChange)
+	}
+	return after
+}
+
+func (opts FixOptions) tolerance() float64 {
+	if opts.Tolerance <= 0 {
+		return epsiron
+	}
+	return opts.Tolerance
+}
+
+func (opts FixOptions) maxIterations() int {
+	if opts.MaxIterations <= 0 {
+		return iterationLimit
+	}
+	return opts.MaxIterations
+}
+
+// Diagnostics is the report of the volatility iteration.
+type Diagnostics struct {
+	Solver     Solver
+	Iterations int
+	Converged  bool
+	// Residual is |f(x)| at the solution, where f is the function of ref[1] step 5.
+	Residual float64
+}
+
+// ConvergenceError is returned when the volatility iteration does not converge.
+type ConvergenceError struct {
+	Diagnostics
+}
+
+// Error returns the string representation of a ConvergenceError.
+func (e *ConvergenceError) Error() string {
+	return fmt.Sprintf("volatility iteration by %s did not converge: iterations=%d residual=%g",
+		e.Solver, e.Iterations, e.Residual)
+}
+
+// volatilityFunc is the function f(x) of ref[1] step 5, where x = ln(sigma'^2)
+type volatilityFunc struct {
+	tau     float64
+	a       float64
+	v       float64
+	sqDelta float64
+	sqPhi   float64
+}
+
+func newVolatilityFunc(fixed Rating, accuracy, improvement, tau float64) volatilityFunc {
+	return volatilityFunc{
+		tau:     tau,
+		a:       math.Log(math.Pow(fixed.sigma, 2)),
+		v:       1.0 / accuracy,
+		sqDelta: math.Pow(improvement, 2),
+		sqPhi:   math.Pow(fixed.phi, 2),
+	}
+}
+
+func (f *volatilityFunc) fx(x float64) float64 {
+	sumVal := f.sqDelta + f.sqPhi + f.v
+	diffVal := f.sqDelta - f.sqPhi - f.v
+	firstTerm := (math.Exp(x) * (diffVal - math.Exp(x))) / (2 * sumVal * sumVal)
+	secondTerm := (x - f.a) / (math.Pow(f.tau, 2))
+	return firstTerm - secondTerm
+}
+
+// dfx is the derivative of fx
+func (f *volatilityFunc) dfx(x float64) float64 {
+	sumVal := f.sqDelta + f.sqPhi + f.v
+	diffVal := f.sqDelta - f.sqPhi - f.v
+	firstTerm := (math.Exp(x) * (diffVal - 2*math.Exp(x))) / (2 * sumVal * sumVal)
+	return firstTerm - 1.0/math.Pow(f.tau, 2)
+}
+
+// bracket returns the initial values A and B of ref[1] step 5.2.
+// If f(B) >= 0 is not found, ok is false.
+func (f *volatilityFunc) bracket(maxIterations int) (A, B float64, ok bool) {
+	A = f.a
+	if f.sqDelta > f.sqPhi+f.v {
+		return A, math.Log(f.sqDelta - f.sqPhi - f.v), true
+	}
+	for k := 1; k < maxIterations+1; k++ {
+		B = f.a - float64(k)*f.tau
+		if f.fx(B) >= 0.0 {
+			return A, B, true
+		}
+	}
+	return A, B, false
+}
+
+// solve returns sigma' and diagnostics
+func (opts FixOptions) solve(f *volatilityFunc) (float64, Diagnostics) {
+	var x float64
+	diag := Diagnostics{Solver: opts.Solver}
+	switch opts.Solver {
+	case Brent:
+		x, diag.Iterations, diag.Converged = f.brent(opts.tolerance(), opts.maxIterations())
+	case Newton:
+		x, diag.Iterations, diag.Converged = f.newton(opts.tolerance(), opts.maxIterations())
+	default:
+		x, diag.Iterations, diag.Converged = f.illinois(opts.tolerance(), opts.maxIterations())
+	}
+	diag.Residual = math.Abs(f.fx(x))
+	sigma := math.Exp(x / 2.0)
+	if math.IsNaN(sigma) || math.IsInf(sigma, 0) || sigma == 0.0 {
+		diag.Converged = false
+	}
+	return sigma, diag
+}
+
+func (f *volatilityFunc) illinois(tolerance float64, maxIterations int) (float64, int, bool) {
+	A, B, ok := f.bracket(maxIterations)
+	if !ok {
+		return A, 0, false
+	}
+	valfA := f.fx(A)
+	valfB := f.fx(B)
+
+	for i := 0; i < maxIterations; i++ {
+		if math.Abs(B-A) <= tolerance {
+			return A, i, true
+		}
+		C := A + ((A-B)*valfA)/(valfB-valfA)
+		valfC := f.fx(C)
+		switch {
+		case valfB*valfC < 0.0:
+			A = B
+			valfA = valfB
+		default:
+			valfA /= 2.0
+		}
+		B = C
+		valfB = valfC
+	}
+	return A, maxIterations, math.Abs(B-A) <= tolerance
+}
+
+func (f *volatilityFunc) brent(tolerance float64, maxIterations int) (float64, int, bool) {
+	a, b, ok := f.bracket(maxIterations)
+	if !ok {
+		return a, 0, false
+	}
+	fa, fb := f.fx(a), f.fx(b)
+	if math.Abs(fa) < math.Abs(fb) {
+		a, b, fa, fb = b, a, fb, fa
+	}
+	c, fc := a, fa
+	d := 0.0
+	bisected := true
+	for i := 0; i < maxIterations; i++ {
+		if fb == 0.0 || math.Abs(b-a) <= tolerance {
+			return b, i, true
+		}
+		var s float64
+		if fa != fc && fb != fc {
+			//inverse quadratic interpolation
+			s = a*fb*fc/((fa-fb)*(fa-fc)) +
+				b*fa*fc/((fb-fa)*(fb-fc)) +
+				c*fa*fb/((fc-fa)*(fc-fb))
+		} else {
+			//secant method
+			s = b - fb*(b-a)/(fb-fa)
+		}
+		switch {
+		case (s-(3*a+b)/4)*(s-b) >= 0,
+			bisected && math.Abs(s-b) >= math.Abs(b-c)/2,
+			!bisected && math.Abs(s-b) >= math.Abs(c-d)/2,
+			bisected && math.Abs(b-c) < tolerance,
+			!bisected && math.Abs(c-d) < tolerance:
+			s = (a + b) / 2
+			bisected = true
+		default:
+			bisected = false
+		}
+		fs := f.fx(s)
+		d, c, fc = c, b, fb
+		if fa*fs < 0 {
+			b, fb = s, fs
+		} else {
+			a, fa = s, fs
+		}
+		if math.Abs(fa) < math.Abs(fb) {
+			a, b, fa, fb = b, a, fb, fa
+		}
+	}
+	return b, maxIterations, math.Abs(b-a) <= tolerance
+}
+
+func (f *volatilityFunc) newton(tolerance float64, maxIterations int) (float64, int, bool) {
+	x := f.a
+	for i := 0; i < maxIterations; i++ {
+		dx := f.fx(x) / f.dfx(x)
+		if math.IsNaN(dx) || math.IsInf(dx, 0) {
+			return x, i, false
+		}
+		x -= dx
+		if math.Abs(dx) <= tolerance {
+			return x, i + 1, true
+		}
+	}
+	return x, maxIterations, false
+}
@@ -0,0 +1,157 @@
+package rating_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/mashiike/rating"
+)
+
+func newSolverEstimated() *rating.Estimated {
+	e := rating.NewEstimated(rating.New(1500.0, 200.0, 0.06))
+	e.ApplyMatch(rating.New(1400.0, 30.0, 0.06), rating.ScoreWin)
+	e.ApplyMatch(rating.New(1550.0, 100.0, 0.06), rating.ScoreLose)
+	e.ApplyMatch(rating.New(1700.0, 300.0, 0.06), rating.ScoreLose)
+	return e
+}
+
+func TestFixWithOptions(t *testing.T) {
+	expected := newSolverEstimated()
+	if err := expected.Fix(0.5); err != nil {
+		t.Fatal(err)
+	}
+	for _, solver := range []rating.Solver{rating.Illinois, rating.Brent, rating.Newton} {
+		t.Run(solver.String(), func(t *testing.T) {
+			e := newSolverEstimated()
+			diag, err := e.FixWithOptions(0.5, rating.FixOptions{Solver: solver})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !diag.Converged || diag.Solver != solver {
+				t.Errorf("unexpected diagnostics: %+v", diag)
+			}
+			if math.Abs(e.Fixed.Volatility()-expected.Fixed.Volatility()) > 1e-6 {
+				t.Errorf("volatility got %v, expected %v", e.Fixed.Volatility(), expected.Fixed.Volatility())
+			}
+			if math.Abs(e.Fixed.Strength()-expected.Fixed.Strength()) > 1e-6 {
+				t.Errorf("strength got %v, expected %v", e.Fixed.Strength(), expected.Fixed.Strength())
+			}
+		})
+	}
+}
+
+func TestFixConvergenceError(t *testing.T) {
+	e := newSolverEstimated()
+	before := e.Fixed
+	diag, err := e.FixWithOptions(0.5, rating.FixOptions{MaxIterations: 1, Tolerance: 1e-15})
+	var convErr *rating.ConvergenceError
+	if !errors.As(err, &convErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diag.Converged || convErr.Iterations != 1 {
+		t.Errorf("unexpected diagnostics: %+v", diag)
+	}
+	if e.Fixed != before {
+		t.Errorf("rating is changed by failed fix: %v", e.Fixed)
+	}
+}
+
+func FuzzFix(f *testing.F) {
+	f.Add(0.5, 0.2, 1.0, 200.0, 0.06)
+	f.Add(1e-9, 1e6, 0.3, 10.0, 0.5)
+	f.Add(1e9, -1e-9, 1.2, 350.0, 1e-6)
+	f.Fuzz(func(t *testing.T, accuracy, improvement, tau, deviation, volatility float64) {
+		if !(accuracy > 0 && tau > 0 && deviation > 0 && volatility > 0) ||
+			math.IsInf(accuracy, 0) || math.IsInf(improvement, 0) || math.IsNaN(improvement) ||
+			math.IsInf(tau, 0) || math.IsInf(deviation, 0) || math.IsInf(volatility, 0) {
+			t.Skip()
+		}
+		for _, solver := range []rating.Solver{rating.Illinois, rating.Brent, rating.Newton} {
+			e := rating.NewEstimated(rating.New(1500.0, deviation, volatility))
+			e.Accuracy = accuracy
+			e.Improvement = improvement
+			before := e.Fixed
+			diag, err := e.FixWithOptions(tau, rating.FixOptions{Solver: solver, MaxIterations: 1000})
+			if err != nil {
+				var convErr *rating.ConvergenceError
+				if !errors.As(err, &convErr) {
+					t.Fatalf("%s unexpected error: %v", solver, err)
+				}
+				if e.Fixed != before {
+					t.Fatalf("%s rating is changed by failed fix", solver)
+				}
+				continue
+			}
+			if !diag.Converged || math.IsNaN(e.Fixed.Volatility()) || math.IsInf(e.Fixed.Volatility(), 0) {
+				t.Fatalf("%s converged to invalid volatility: %+v %v", solver, diag, e.Fixed)
+			}
+		}
+	})
+}
+
+func TestFixSafeguards(t *testing.T) {
+	base := rating.DefaultScale
+	cases := []struct {
+		name  string
+		opts  rating.FixOptions
+		check func(before, after rating.Rating) bool
+	}{
+		{
+			name: "MinPhi",
+			opts: rating.FixOptions{MinPhi: 180.0 / base.Factor},
+			check: func(before, after rating.Rating) bool {
+				return after.Deviation() == 180.0
+			},
+		},
+		{
+			name: "MaxChange",
+			opts: rating.FixOptions{MaxChange: 10.0 / base.Factor},
+			check: func(before, after rating.Rating) bool {
+				return math.Abs(after.Diff(before)+10.0) < 1e-9
+			},
+		},
+		{
+			name: "Floor",
+			opts: rating.FixOptions{Floor: (1490.0 - base.Center) / base.Factor, HasFloor: true},
+			check: func(before, after rating.Rating) bool {
+				return after.Strength() == 1490.0
+			},
+		},
+		{
+			name: "FloorAboveRating",
+			opts: rating.FixOptions{Floor: (1600.0 - base.Center) / base.Factor, HasFloor: true},
+			check: func(before, after rating.Rating) bool {
+				return after == rating.NewGlicko2(before.Mu(), after.Phi(), after.Sigma())
+			},
+		},
+		{
+			name: "FloorAndMaxChange",
+			opts: rating.FixOptions{Floor: (1450.0 - base.Center) / base.Factor, HasFloor: true, MaxChange: 10.0 / base.Factor},
+			check: func(before, after rating.Rating) bool {
+				return math.Abs(after.Diff(before)+10.0) < 1e-9
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := newSolverEstimated()
+			before := e.Fixed
+			if _, err := e.FixWithOptions(0.5, c.opts); err != nil {
+				t.Fatal(err)
+			}
+			if !c.check(before, e.Fixed) {
+				t.Errorf("unexpected rating: before %v, after %v", before, e.Fixed)
+			}
+		})
+	}
+
+	//no matches, the rating below the floor is not raised
+	e := rating.NewEstimated(rating.New(1400.0, 30.0, 0.06))
+	if _, err := e.FixWithOptions(0.5, rating.FixOptions{MinPhi: 100.0 / base.Factor, Floor: 0.0, HasFloor: true}); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(e.Fixed.Deviation()-100.0) > 1e-9 || math.Abs(e.Fixed.Strength()-1400.0) > 1e-9 {
+		t.Errorf("safeguards are not enforced without matches: %v", e.Fixed)
+	}
+}
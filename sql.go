@@ -0,0 +1,91 @@
+package rating
+
+import (
+	"database/sql/driver"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// scanLayouts are the layouts tried by Rating.Scan, the more detailed one first
+var scanLayouts = []string{
+	DefaultFormat,
+	DetailFormat,
+	WithRangeFormat,
+	PlusMinusFormat,
+	CSVFormat,
+	StrengthOnlyFormat,
+}
+
+// Value implements the driver.Valuer interface.
+// The rating is stored as the lossless JSON of StructuredRating, the text layouts are only accepted by Scan.
+func (r Rating) Value() (driver.Value, error) {
+	return StructuredRating{r}.MarshalJSON()
+}
+
+// Scan implements the sql.Scanner interface.
+// It accepts the binary form of MarshalBinary or MarshalBinaryCompact, a JSON object of StructuredRating,
+// or text in any of the predefined layouts such as DefaultFormat, PlusMinusFormat and CSVFormat.
+func (r *Rating) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		if isRatingBinary(v) {
+			return r.UnmarshalBinary(v)
+		}
+		return r.scanText(string(v))
+	case string:
+		return r.scanText(v)
+	case nil:
+		return errors.New("Rating.Scan: NULL is not a rating")
+	}
+	return errors.Errorf("Rating.Scan: unsupported type %T", src)
+}
+
+func isRatingBinary(b []byte) bool {
+	return (len(b) == 25 && b[0] == ratingBinaryVersion) || (len(b) == 13 && b[0] == ratingBinaryVersionCompact)
+}
+
+func (r *Rating) scanText(value string) error {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "{") {
+		return r.unmarshalStructured([]byte(value))
+	}
+	var err error
+	for _, layout := range scanLayouts {
+		var parsed Rating
+		if parsed, err = Parse(layout, value); err == nil {
+			*r = parsed
+			return nil
+		}
+	}
+	return errors.Wrapf(err, "Rating.Scan: %q matches no layout", value)
+}
+
+// Value implements the driver.Valuer interface.
+// The estimated state is stored as the lossless JSON of MarshalJSON, and nil as NULL.
+func (e *Estimated) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return e.MarshalJSON()
+}
+
+// Scan implements the sql.Scanner interface.
+// It accepts the JSON of MarshalJSON, or the binary form of MarshalBinary or MarshalBinaryCompact.
+func (e *Estimated) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		return errors.New("Estimated.Scan: NULL is not an estimated state")
+	default:
+		return errors.Errorf("Estimated.Scan: unsupported type %T", src)
+	}
+	if len(data) > 0 && (data[0] == estimatedBinaryVersion || data[0] == estimatedBinaryVersionCompact) {
+		return e.UnmarshalBinary(data)
+	}
+	return e.UnmarshalJSON(data)
+}
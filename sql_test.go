@@ -0,0 +1,198 @@
+package rating_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/mashiike/rating"
+)
+
+// fakeDriver stores the values of "INSERT" in a single column table, and "SELECT" returns them in order.
+type fakeDriver struct {
+	mu     sync.Mutex
+	values []driver.Value
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{driver: d}, nil
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{driver: c.driver, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+type fakeStmt struct {
+	driver *fakeDriver
+	query  string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	if s.query == "INSERT" {
+		return 1
+	}
+	return 0
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	s.driver.values = append(s.driver.values, args[0])
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	values := make([]driver.Value, len(s.driver.values))
+	copy(values, s.driver.values)
+	s.driver.values = nil
+	return &fakeRows{values: values}, nil
+}
+
+type fakeRows struct {
+	values []driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"rating"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+	return nil
+}
+
+var fakeDB = func() *sql.DB {
+	sql.Register("ratingfake", &fakeDriver{})
+	db, err := sql.Open("ratingfake", "")
+	if err != nil {
+		panic(err)
+	}
+	db.SetMaxOpenConns(1)
+	return db
+}()
+
+func insert(t *testing.T, values ...interface{}) {
+	t.Helper()
+	for _, v := range values {
+		if _, err := fakeDB.Exec("INSERT", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRatingSQL(t *testing.T) {
+	r := rating.New(1623.4, 81.5, 0.0612)
+	bin, _ := r.MarshalBinary()
+	compact, _ := r.MarshalBinaryCompact()
+	insert(t,
+		r,
+		bin,
+		compact,
+		"1623.4p-163.0",
+		"1623.4 (1460.4-1786.4)",
+		[]byte("1623.4,81.5,0.0612"),
+		`{"strength":1623.4,"deviation":81.5,"volatility":0.0612}`,
+	)
+	rows, err := fakeDB.Query("SELECT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		var got rating.Rating
+		if err := rows.Scan(&got); err != nil {
+			t.Fatalf("row %d: %v", n, err)
+		}
+		if got.Format(rating.PlusMinusFormat) != "1623.4p-163.0" {
+			t.Errorf("row %d: got %s", n, got.Format(rating.DefaultFormat))
+		}
+		n++
+	}
+	if n != 7 {
+		t.Errorf("scanned %d rows, want 7", n)
+	}
+
+	//the value of Value is scanned without loss
+	exact := rating.New(1523.456789, 87.654321, 0.0612345678)
+	v, err := exact.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got rating.Rating
+	if err := got.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if got != exact {
+		t.Errorf("Scan(Value()) got %#v, want %#v", got, exact)
+	}
+	if err := got.Scan("not a rating"); err == nil {
+		t.Error("invalid text is accepted")
+	}
+	if err := got.Scan(nil); err == nil {
+		t.Error("NULL is accepted")
+	}
+	if err := got.Scan(int64(1500)); err == nil {
+		t.Error("int64 is accepted")
+	}
+}
+
+func TestEstimatedSQL(t *testing.T) {
+	e := rating.NewEstimated(rating.New(1623.4, 81.5, 0.0612))
+	if err := e.ApplyMatch(rating.Default(0.06), rating.ScoreLose); err != nil {
+		t.Fatal(err)
+	}
+	bin, _ := e.MarshalBinary()
+	insert(t, e, bin)
+	rows, err := fakeDB.Query("SELECT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		got := rating.NewEstimated(rating.Rating{})
+		if err := rows.Scan(got); err != nil {
+			t.Fatalf("row %d: %v", n, err)
+		}
+		if got.Fixed != e.Fixed || got.Accuracy != e.Accuracy || got.Improvement != e.Improvement {
+			t.Errorf("row %d: got %v, want %v", n, got, e)
+		}
+		n++
+	}
+	if n != 2 {
+		t.Errorf("scanned %d rows, want 2", n)
+	}
+	var null *rating.Estimated
+	if v, err := null.Value(); v != nil || err != nil {
+		t.Errorf("nil should be NULL: %v %v", v, err)
+	}
+}